@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// Returned when a whole chat action is requested on a chat without a known last message
+var errNoLastMessage = errors.New("No message known for this chat, it needs a message sent or received since wuzapi started")
+
+// Returns the timestamp and key of the last message of a chat, as seen in messages and
+// history syncs since wuzapi started
+func chatLastMessage(client *whatsmeow.Client, userid int, chat types.JID) (time.Time, *waCommon.MessageKey, bool) {
+	last, found := recallLastMessage(userid, chat)
+	if !found {
+		return time.Time{}, nil, false
+	}
+	fromMe := last.Sender.User == client.Store.ID.User || (client.Store.LID.User != "" && last.Sender.User == client.Store.LID.User)
+	key := &waCommon.MessageKey{
+		RemoteJID: proto.String(chat.String()),
+		FromMe:    proto.Bool(fromMe),
+		ID:        proto.String(last.ID),
+	}
+	if !fromMe && chat.Server == types.GroupServer {
+		key.Participant = proto.String(last.Sender.String())
+	}
+	return last.Timestamp, key, true
+}
+
+// whatsmeow only ships builders for mute, pin and archive. The patches below follow the
+// index and version used by WhatsApp Web for the remaining chat actions. They apply up
+// to the last message of the chat, so it has to be known
+
+func chatMessageRange(client *whatsmeow.Client, userid int, chat types.JID) (*waSyncAction.SyncActionMessageRange, error) {
+	timestamp, key, found := chatLastMessage(client, userid, chat)
+	if !found {
+		return nil, errNoLastMessage
+	}
+	return &waSyncAction.SyncActionMessageRange{
+		LastMessageTimestamp: proto.Int64(timestamp.Unix()),
+		Messages: []*waSyncAction.SyncActionMessage{{
+			Key:       key,
+			Timestamp: proto.Int64(timestamp.Unix()),
+		}},
+	}, nil
+}
+
+func buildMarkChatAsRead(client *whatsmeow.Client, userid int, target types.JID, read bool) (appstate.PatchInfo, error) {
+	messageRange, err := chatMessageRange(client, userid, target)
+	if err != nil {
+		return appstate.PatchInfo{}, err
+	}
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularLow,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexMarkChatAsRead, target.String()},
+			Version: 3,
+			Value: &waSyncAction.SyncActionValue{
+				MarkChatAsReadAction: &waSyncAction.MarkChatAsReadAction{
+					Read:         proto.Bool(read),
+					MessageRange: messageRange,
+				},
+			},
+		}},
+	}, nil
+}
+
+// Clears all messages of a chat, keeping starred messages and downloaded media
+func buildClearChat(client *whatsmeow.Client, userid int, target types.JID) (appstate.PatchInfo, error) {
+	messageRange, err := chatMessageRange(client, userid, target)
+	if err != nil {
+		return appstate.PatchInfo{}, err
+	}
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexClearChat, target.String(), "0", "0"},
+			Version: 6,
+			Value: &waSyncAction.SyncActionValue{
+				ClearChatAction: &waSyncAction.ClearChatAction{
+					MessageRange: messageRange,
+				},
+			},
+		}},
+	}, nil
+}
+
+// Deletes a chat from the chat list, along with its media
+func buildDeleteChat(client *whatsmeow.Client, userid int, target types.JID) (appstate.PatchInfo, error) {
+	messageRange, err := chatMessageRange(client, userid, target)
+	if err != nil {
+		return appstate.PatchInfo{}, err
+	}
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexDeleteChat, target.String(), "1"},
+			Version: 6,
+			Value: &waSyncAction.SyncActionValue{
+				DeleteChatAction: &waSyncAction.DeleteChatAction{
+					MessageRange: messageRange,
+				},
+			},
+		}},
+	}, nil
+}
+
+// Chat list change made from another device, sent in ChatSetting webhooks
+type chatSetting struct {
+	JID          types.JID
+	Action       string     // archive, unarchive, pin, unpin, mute, unmute, read, unread, clear or delete
+	MutedUntil   *time.Time `json:",omitempty"`
+	Timestamp    time.Time
+	FromFullSync bool `json:"-"`
+}
+
+// Converts chat related app state events to a chatSetting, returns nil for anything else
+func parseChatSetting(rawEvt interface{}) *chatSetting {
+	switch evt := rawEvt.(type) {
+	case *events.Archive:
+		setting := &chatSetting{JID: evt.JID, Action: "unarchive", Timestamp: evt.Timestamp, FromFullSync: evt.FromFullSync}
+		if evt.Action.GetArchived() {
+			setting.Action = "archive"
+		}
+		return setting
+	case *events.Pin:
+		setting := &chatSetting{JID: evt.JID, Action: "unpin", Timestamp: evt.Timestamp, FromFullSync: evt.FromFullSync}
+		if evt.Action.GetPinned() {
+			setting.Action = "pin"
+		}
+		return setting
+	case *events.Mute:
+		setting := &chatSetting{JID: evt.JID, Action: "unmute", Timestamp: evt.Timestamp, FromFullSync: evt.FromFullSync}
+		if evt.Action.GetMuted() {
+			setting.Action = "mute"
+			// Chats muted forever have no end timestamp (or -1)
+			if end := evt.Action.GetMuteEndTimestamp(); end > 0 {
+				until := time.UnixMilli(end)
+				setting.MutedUntil = &until
+			}
+		}
+		return setting
+	case *events.MarkChatAsRead:
+		setting := &chatSetting{JID: evt.JID, Action: "unread", Timestamp: evt.Timestamp, FromFullSync: evt.FromFullSync}
+		if evt.Action.GetRead() {
+			setting.Action = "read"
+		}
+		return setting
+	case *events.ClearChat:
+		return &chatSetting{JID: evt.JID, Action: "clear", Timestamp: evt.Timestamp, FromFullSync: evt.FromFullSync}
+	case *events.DeleteChat:
+		return &chatSetting{JID: evt.JID, Action: "delete", Timestamp: evt.Timestamp, FromFullSync: evt.FromFullSync}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// Keeps, per user, the disappearing messages timer (in seconds, 0 when off) of the chats
+// we know about. Timers are learned from history sync, group info, incoming messages and
+// the timers set through the API. The default timer of the account, which new 1:1 chats
+// start with, is kept in the users table as WhatsApp offers no way to read it back
+type EphemeralStore struct {
+	sync.RWMutex
+	timers   map[int]map[types.JID]uint32
+	defaults map[int]uint32
+}
+
+func NewEphemeralStore() *EphemeralStore {
+	return &EphemeralStore{
+		timers:   make(map[int]map[types.JID]uint32),
+		defaults: make(map[int]uint32),
+	}
+}
+
+func (es *EphemeralStore) Set(userID int, chat types.JID, seconds uint32) {
+	es.Lock()
+	defer es.Unlock()
+	if es.timers[userID] == nil {
+		es.timers[userID] = make(map[types.JID]uint32)
+	}
+	es.timers[userID][chat.ToNonAD()] = seconds
+}
+
+func (es *EphemeralStore) Get(userID int, chat types.JID) (uint32, bool) {
+	es.RLock()
+	defer es.RUnlock()
+	seconds, ok := es.timers[userID][chat.ToNonAD()]
+	return seconds, ok
+}
+
+func (es *EphemeralStore) SetDefault(userID int, seconds uint32) {
+	es.Lock()
+	defer es.Unlock()
+	es.defaults[userID] = seconds
+}
+
+func (es *EphemeralStore) Default(userID int) uint32 {
+	es.RLock()
+	defer es.RUnlock()
+	return es.defaults[userID]
+}
+
+func (es *EphemeralStore) DeleteUser(userID int) {
+	es.Lock()
+	defer es.Unlock()
+	delete(es.timers, userID)
+	delete(es.defaults, userID)
+}
+
+// Loads the default timer saved for a user
+func loadDefaultEphemeral(db *sqlx.DB, userID int) {
+	var seconds uint32
+	if err := db.Get(&seconds, "SELECT default_ephemeral FROM users WHERE id=$1", userID); err != nil {
+		log.Warn().Err(err).Int("userid", userID).Msg("Could not load default disappearing timer")
+		return
+	}
+	ephemeralStore.SetDefault(userID, seconds)
+}
+
+// Saves the default timer of a user, for new 1:1 chats
+func saveDefaultEphemeral(db *sqlx.DB, userID int, seconds uint32) error {
+	if _, err := db.Exec("UPDATE users SET default_ephemeral=$1 WHERE id=$2", seconds, userID); err != nil {
+		return err
+	}
+	ephemeralStore.SetDefault(userID, seconds)
+	return nil
+}
+
+// Learns the timers of all chats included in a history sync
+func (es *EphemeralStore) UpdateFromHistory(userID int, conversations []*waHistorySync.Conversation) {
+	for _, conv := range conversations {
+		chat, err := types.ParseJID(conv.GetID())
+		if err != nil {
+			continue
+		}
+		es.Set(userID, chat, conv.GetEphemeralExpiration())
+	}
+}
+
+// Learns the timer of a chat from an incoming or outgoing message. Timer changes arrive as
+// protocol messages, any other message carries the current timer in its ContextInfo
+func (es *EphemeralStore) UpdateFromMessage(userID int, chat types.JID, msg *waE2E.Message) {
+	if chat.Server != types.DefaultUserServer && chat.Server != types.HiddenUserServer {
+		return
+	}
+	if protocol := msg.GetProtocolMessage(); protocol != nil {
+		if protocol.GetType() == waE2E.ProtocolMessage_EPHEMERAL_SETTING {
+			es.Set(userID, chat, protocol.GetEphemeralExpiration())
+		}
+		return
+	}
+	if contextInfo := getContextInfo(msg); contextInfo != nil {
+		es.Set(userID, chat, contextInfo.GetExpiration())
+	}
+}
+
+// Returns the disappearing messages timer of a chat. Group timers we don't know yet are
+// fetched from the group info, unknown 1:1 chats are new and get the default timer
+func chatExpiration(client *whatsmeow.Client, userID int, chat types.JID) uint32 {
+	if seconds, ok := ephemeralStore.Get(userID, chat); ok {
+		return seconds
+	}
+	if chat.Server == types.DefaultUserServer || chat.Server == types.HiddenUserServer {
+		return ephemeralStore.Default(userID)
+	}
+	if chat.Server != types.GroupServer {
+		return 0
+	}
+	info, err := client.GetGroupInfo(chat)
+	if err != nil {
+		log.Warn().Err(err).Str("group", chat.String()).Msg("Could not get group disappearing timer")
+		return 0
+	}
+	var seconds uint32
+	if info.IsEphemeral {
+		seconds = info.DisappearingTimer
+	}
+	ephemeralStore.Set(userID, chat, seconds)
+	return seconds
+}
+
+// Parses a disappearing messages duration (24h, 7d, 90d or off)
+func parseDisappearingTimer(duration string) (time.Duration, bool) {
+	switch strings.ToLower(duration) {
+	case "24h", "1d":
+		return whatsmeow.DisappearingTimer24Hours, true
+	case "7d":
+		return whatsmeow.DisappearingTimer7Days, true
+	case "90d":
+		return whatsmeow.DisappearingTimer90Days, true
+	case "off", "0":
+		return whatsmeow.DisappearingTimerOff, true
+	}
+	return 0, false
+}
+
+// Sets ContextInfo.Expiration on an outgoing message so it follows the disappearing
+// messages timer of the chat
+func applyExpiration(client *whatsmeow.Client, userID int, chat types.JID, msg *waE2E.Message) {
+	seconds := chatExpiration(client, userID, chat)
+	if seconds == 0 {
+		return
+	}
+	contextInfo := getContextInfo(msg)
+	if contextInfo == nil {
+		contextInfo = &waE2E.ContextInfo{}
+		setContextInfo(msg, contextInfo)
+	}
+	contextInfo.Expiration = proto.Uint32(seconds)
+}
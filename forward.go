@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// Builds a forwarded copy of a message. Media keeps pointing to the original upload, so
+// nothing has to be downloaded. The forwarding score counts how many times the content
+// was forwarded, WhatsApp shows "Forwarded many times" from 5 on
+func forwardMessage(original *waE2E.Message) (*waE2E.Message, error) {
+	if original.Conversation == nil && original.ExtendedTextMessage == nil && original.ImageMessage == nil &&
+		original.VideoMessage == nil && original.PtvMessage == nil && original.AudioMessage == nil &&
+		original.DocumentMessage == nil && original.StickerMessage == nil && original.LocationMessage == nil &&
+		original.ContactMessage == nil && original.ContactsArrayMessage == nil {
+		return nil, errors.New("Message type cannot be forwarded")
+	}
+	// View once media can only be opened by its recipient, WhatsApp does not allow forwarding it
+	if original.GetImageMessage().GetViewOnce() || original.GetVideoMessage().GetViewOnce() || original.GetAudioMessage().GetViewOnce() {
+		return nil, errors.New("View once messages cannot be forwarded")
+	}
+
+	msg := proto.Clone(original).(*waE2E.Message)
+	msg.MessageContextInfo = nil
+
+	var score uint32
+	if contextInfo := getContextInfo(msg); contextInfo != nil {
+		score = contextInfo.GetForwardingScore()
+	}
+	setContextInfo(msg, &waE2E.ContextInfo{
+		IsForwarded:     proto.Bool(true),
+		ForwardingScore: proto.Uint32(score + 1),
+	})
+	return msg, nil
+}
+
+// Downloads the media of a message and uploads it again, for media whose original upload
+// is no longer available on the WhatsApp servers
+func reuploadMedia(client *whatsmeow.Client, msg *waE2E.Message) error {
+	reupload := func(media whatsmeow.DownloadableMessage) (whatsmeow.UploadResponse, error) {
+		data, err := client.Download(media)
+		if err != nil {
+			return whatsmeow.UploadResponse{}, fmt.Errorf("Failed to download media: %v", err)
+		}
+		uploaded, err := client.Upload(context.Background(), data, whatsmeow.GetMediaType(media))
+		if err != nil {
+			return whatsmeow.UploadResponse{}, fmt.Errorf("Failed to upload file: %v", err)
+		}
+		return uploaded, nil
+	}
+
+	switch {
+	case msg.ImageMessage != nil:
+		m := msg.ImageMessage
+		uploaded, err := reupload(m)
+		if err != nil {
+			return err
+		}
+		m.URL, m.DirectPath, m.MediaKey = proto.String(uploaded.URL), proto.String(uploaded.DirectPath), uploaded.MediaKey
+		m.FileEncSHA256, m.FileSHA256, m.FileLength = uploaded.FileEncSHA256, uploaded.FileSHA256, proto.Uint64(uploaded.FileLength)
+	case msg.VideoMessage != nil || msg.PtvMessage != nil:
+		m := msg.VideoMessage
+		if m == nil {
+			m = msg.PtvMessage
+		}
+		uploaded, err := reupload(m)
+		if err != nil {
+			return err
+		}
+		m.URL, m.DirectPath, m.MediaKey = proto.String(uploaded.URL), proto.String(uploaded.DirectPath), uploaded.MediaKey
+		m.FileEncSHA256, m.FileSHA256, m.FileLength = uploaded.FileEncSHA256, uploaded.FileSHA256, proto.Uint64(uploaded.FileLength)
+	case msg.AudioMessage != nil:
+		m := msg.AudioMessage
+		uploaded, err := reupload(m)
+		if err != nil {
+			return err
+		}
+		m.URL, m.DirectPath, m.MediaKey = proto.String(uploaded.URL), proto.String(uploaded.DirectPath), uploaded.MediaKey
+		m.FileEncSHA256, m.FileSHA256, m.FileLength = uploaded.FileEncSHA256, uploaded.FileSHA256, proto.Uint64(uploaded.FileLength)
+	case msg.DocumentMessage != nil:
+		m := msg.DocumentMessage
+		uploaded, err := reupload(m)
+		if err != nil {
+			return err
+		}
+		m.URL, m.DirectPath, m.MediaKey = proto.String(uploaded.URL), proto.String(uploaded.DirectPath), uploaded.MediaKey
+		m.FileEncSHA256, m.FileSHA256, m.FileLength = uploaded.FileEncSHA256, uploaded.FileSHA256, proto.Uint64(uploaded.FileLength)
+	case msg.StickerMessage != nil:
+		m := msg.StickerMessage
+		uploaded, err := reupload(m)
+		if err != nil {
+			return err
+		}
+		m.URL, m.DirectPath, m.MediaKey = proto.String(uploaded.URL), proto.String(uploaded.DirectPath), uploaded.MediaKey
+		m.FileEncSHA256, m.FileSHA256, m.FileLength = uploaded.FileEncSHA256, uploaded.FileSHA256, proto.Uint64(uploaded.FileLength)
+	}
+	return nil
+}
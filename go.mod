@@ -20,6 +20,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/vincent-petithory/dataurl v1.0.0
+	golang.org/x/net v0.39.0
 	modernc.org/sqlite v1.37.0
 )
 
@@ -47,7 +48,6 @@ require (
 	go.mau.fi/libsignal v0.1.2 // indirect
 	go.mau.fi/util v0.8.6 // indirect
 	golang.org/x/crypto v0.37.0 // indirect
-	golang.org/x/net v0.39.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 	rsc.io/qr v0.2.0 // indirect
 )
@@ -1,33 +1,33 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"image"
-	"image/jpeg"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/nfnt/resize"
 	"github.com/patrickmn/go-cache"
 	"github.com/rs/zerolog/log"
 	"github.com/vincent-petithory/dataurl"
 	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
 
 	"go.mau.fi/whatsmeow/proto/waCommon"
 	"go.mau.fi/whatsmeow/proto/waE2E"
 
 	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -39,7 +39,7 @@ func (v Values) Get(key string) string {
 	return v.m[key]
 }
 
-var messageTypes = []string{"Message", "ReadReceipt", "Presence", "HistorySync", "ChatPresence", "All"}
+var messageTypes = []string{"Message", "ReadReceipt", "Presence", "HistorySync", "ChatPresence", "GroupJoinRequest", "PrivacySettings", "Blocklist", "PushName", "BusinessName", "NewsletterJoin", "NewsletterLeave", "NewsletterMuteChange", "NewsletterLiveUpdate", "Status", "ChatSetting", "Location", "All"}
 
 func (s *server) authadmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -61,6 +61,9 @@ func (s *server) authalice(next http.Handler) http.Handler {
 		webhook := ""
 		jid := ""
 		events := ""
+		mediaDelivery := ""
+		mediaTypes := ""
+		mediaMaxSize := ""
 
 		// Get token from headers or uri parameters
 		token := r.Header.Get("token")
@@ -72,25 +75,28 @@ func (s *server) authalice(next http.Handler) http.Handler {
 		if !found {
 			log.Info().Msg("Looking for user information in DB")
 			// Checks DB from matching user and store user values in context
-			rows, err := s.db.Query("SELECT id,webhook,jid,events FROM users WHERE token=$1 LIMIT 1", token)
+			rows, err := s.db.Query("SELECT id,webhook,jid,events,media_delivery,media_types,media_max_size FROM users WHERE token=$1 LIMIT 1", token)
 			if err != nil {
 				s.Respond(w, r, http.StatusInternalServerError, err)
 				return
 			}
 			defer rows.Close()
 			for rows.Next() {
-				err = rows.Scan(&txtid, &webhook, &jid, &events)
+				err = rows.Scan(&txtid, &webhook, &jid, &events, &mediaDelivery, &mediaTypes, &mediaMaxSize)
 				if err != nil {
 					s.Respond(w, r, http.StatusInternalServerError, err)
 					return
 				}
 				userid, _ = strconv.Atoi(txtid)
 				v := Values{map[string]string{
-					"Id":      txtid,
-					"Jid":     jid,
-					"Webhook": webhook,
-					"Token":   token,
-					"Events":  events,
+					"Id":            txtid,
+					"Jid":           jid,
+					"Webhook":       webhook,
+					"Token":         token,
+					"Events":        events,
+					"MediaDelivery": mediaDelivery,
+					"MediaTypes":    mediaTypes,
+					"MediaMaxSize":  mediaMaxSize,
 				}}
 
 				userinfocache.Set(token, v, cache.NoExpiration)
@@ -441,6 +447,124 @@ func (s *server) SetWebhook() http.HandlerFunc {
 	}
 }
 
+// Gets how incoming media is delivered in webhooks
+func (s *server) GetMediaDelivery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := mediaDeliveryFromUserInfo(r.Context().Value("userinfo").(Values))
+		if config.Types == nil {
+			config.Types = []string{}
+		}
+		responseJson, err := json.Marshal(config)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+	}
+}
+
+// Sets how incoming media is delivered in webhooks: inline base64, by reference or as
+// a link to a copy hosted by wuzapi, with optional type and size filters
+func (s *server) SetMediaDelivery() http.HandlerFunc {
+
+	type mediaDeliveryStruct struct {
+		Mode    string
+		Types   []string
+		MaxSize uint64
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		token := r.Context().Value("userinfo").(Values).Get("Token")
+		userid, _ := strconv.Atoi(txtid)
+
+		decoder := json.NewDecoder(r.Body)
+		var t mediaDeliveryStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		t.Mode = strings.ToLower(t.Mode)
+		if !Find(mediaDeliveryModes, t.Mode) {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid Mode, use base64, reference or url"))
+			return
+		}
+		var types []string
+		for _, kind := range t.Types {
+			kind = strings.ToLower(kind)
+			if !Find(mediaDeliveryTypes, kind) {
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid type %s in Types, use image, audio, video, document or sticker", kind)))
+				return
+			}
+			if !Find(types, kind) {
+				types = append(types, kind)
+			}
+		}
+		typestring := strings.Join(types, ",")
+		if t.Mode == mediaDeliveryURL && *publicURL == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Mode url needs wuzapi to be started with -publicurl"))
+			return
+		}
+
+		_, err = s.db.Exec("UPDATE users SET media_delivery=$1, media_types=$2, media_max_size=$3 WHERE id=$4", t.Mode, typestring, t.MaxSize, userid)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not set media delivery: %v", err)))
+			return
+		}
+
+		v := updateUserInfo(r.Context().Value("userinfo"), "MediaDelivery", t.Mode)
+		v = updateUserInfo(v, "MediaTypes", typestring)
+		v = updateUserInfo(v, "MediaMaxSize", strconv.FormatUint(t.MaxSize, 10))
+		userinfocache.Set(token, v, cache.NoExpiration)
+
+		config := mediaDeliveryFromUserInfo(v.(Values))
+		if config.Types == nil {
+			config.Types = []string{}
+		}
+		responseJson, err := json.Marshal(config)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+	}
+}
+
+// Serves media received in the url delivery mode
+func (s *server) GetHostedMedia() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		name := mux.Vars(r)["name"]
+		file, err := openHostedMedia(s.exPath, userid, name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				s.Respond(w, r, http.StatusNotFound, errors.New("Media not found or expired"))
+			} else {
+				s.Respond(w, r, http.StatusBadRequest, err)
+			}
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if info.IsDir() {
+			s.Respond(w, r, http.StatusNotFound, errors.New("Media not found or expired"))
+			return
+		}
+		http.ServeContent(w, r, name, info.ModTime(), file)
+	}
+}
+
 // Gets QR code encoded in Base64
 func (s *server) GetQR() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -515,6 +639,12 @@ func (s *server) Logout() http.HandlerFunc {
 				} else {
 					log.Info().Str("jid", jid).Msg("Logged out")
 					clientManager.DeleteWhatsmeowClient(userid)
+					deletePresenceSubscriptions(s.db, userid)
+					ephemeralStore.DeleteUser(userid)
+					liveLocationStore.DeleteUser(userid)
+					if err := saveDefaultEphemeral(s.db, userid, 0); err != nil {
+						log.Error().Err(err).Msg("Could not reset default disappearing timer")
+					}
 					killchannel[userid] <- true
 				}
 			} else {
@@ -627,12 +757,14 @@ func (s *server) GetStatus() http.HandlerFunc {
 func (s *server) SendDocument() http.HandlerFunc {
 
 	type documentStruct struct {
-		Caption     string
-		Phone       string
-		Document    string
-		FileName    string
-		Id          string
-		ContextInfo waE2E.ContextInfo
+		Caption      string
+		Phone        string
+		Document     string
+		FileName     string
+		Id           string
+		MentionAll   bool
+		HideMentions bool
+		ContextInfo  waE2E.ContextInfo
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -684,6 +816,18 @@ func (s *server) SendDocument() http.HandlerFunc {
 			msgid = t.Id
 		}
 
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		contextInfo, t.Caption, err = addMentions(clientManager.GetWhatsmeowClient(userid), contextInfo, recipient, t.Caption, t.MentionAll, t.HideMentions)
+		if err != nil {
+			s.Respond(w, r, mentionErrorStatus(err), err)
+			return
+		}
+
 		var uploaded whatsmeow.UploadResponse
 		var filedata []byte
 
@@ -717,25 +861,15 @@ func (s *server) SendDocument() http.HandlerFunc {
 			Caption:       proto.String(t.Caption),
 		}}
 
-		if t.ContextInfo.StanzaID != nil {
-			msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{
-				StanzaID:      proto.String(*t.ContextInfo.StanzaID),
-				Participant:   proto.String(*t.ContextInfo.Participant),
-				QuotedMessage: &waE2E.Message{Conversation: proto.String("")},
-			}
-		}
-		if t.ContextInfo.MentionedJID != nil {
-			if msg.ExtendedTextMessage.ContextInfo == nil {
-				msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
-			}
-			msg.ExtendedTextMessage.ContextInfo.MentionedJID = t.ContextInfo.MentionedJID
-		}
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
 
 		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
 
 		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
@@ -839,25 +973,20 @@ func (s *server) SendAudio() http.HandlerFunc {
 			PTT:           &ptt,
 		}}
 
-		if t.ContextInfo.StanzaID != nil {
-			msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{
-				StanzaID:      proto.String(*t.ContextInfo.StanzaID),
-				Participant:   proto.String(*t.ContextInfo.Participant),
-				QuotedMessage: &waE2E.Message{Conversation: proto.String("")},
-			}
-		}
-		if t.ContextInfo.MentionedJID != nil {
-			if msg.ExtendedTextMessage.ContextInfo == nil {
-				msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
-			}
-			msg.ExtendedTextMessage.ContextInfo.MentionedJID = t.ContextInfo.MentionedJID
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
 		}
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
 
 		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
 
 		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
@@ -875,11 +1004,13 @@ func (s *server) SendAudio() http.HandlerFunc {
 func (s *server) SendImage() http.HandlerFunc {
 
 	type imageStruct struct {
-		Phone       string
-		Image       string
-		Caption     string
-		Id          string
-		ContextInfo waE2E.ContextInfo
+		Phone        string
+		Image        string
+		Caption      string
+		Id           string
+		MentionAll   bool
+		HideMentions bool
+		ContextInfo  waE2E.ContextInfo
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -925,90 +1056,49 @@ func (s *server) SendImage() http.HandlerFunc {
 			msgid = t.Id
 		}
 
-		var uploaded whatsmeow.UploadResponse
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		contextInfo, t.Caption, err = addMentions(clientManager.GetWhatsmeowClient(userid), contextInfo, recipient, t.Caption, t.MentionAll, t.HideMentions)
+		if err != nil {
+			s.Respond(w, r, mentionErrorStatus(err), err)
+			return
+		}
+
 		var filedata []byte
-		var thumbnailBytes []byte
 
 		if t.Image[0:10] == "data:image" {
 			var dataURL, err = dataurl.DecodeString(t.Image)
 			if err != nil {
 				s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
 				return
-			} else {
-				filedata = dataURL.Data
-				uploaded, err = clientManager.GetWhatsmeowClient(userid).Upload(context.Background(), filedata, whatsmeow.MediaImage)
-				if err != nil {
-					s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to upload file: %v", err)))
-					return
-				}
-			}
-
-			// decode jpeg into image.Image
-			reader := bytes.NewReader(filedata)
-			img, _, err := image.Decode(reader)
-			if err != nil {
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not decode image for thumbnail preparation: %v", err)))
-				return
-			}
-
-			// resize to width 72 using Lanczos resampling and preserve aspect ratio
-			m := resize.Thumbnail(72, 72, img, resize.Lanczos3)
-
-			tmpFile, err := os.CreateTemp("", "resized-*.jpg")
-			if err != nil {
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create temp file for thumbnail: %v", err)))
-				return
-			}
-			defer tmpFile.Close()
-
-			// write new image to file
-			if err := jpeg.Encode(tmpFile, m, nil); err != nil {
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to encode jpeg: %v", err)))
-				return
-			}
-
-			thumbnailBytes, err = os.ReadFile(tmpFile.Name())
-			if err != nil {
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to read %s: %v", tmpFile.Name(), err)))
-				return
 			}
-
+			filedata = dataURL.Data
 		} else {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Image data should start with \"data:image/png;base64,\""))
 			return
 		}
 
-		msg := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
-			Caption:       proto.String(t.Caption),
-			URL:           proto.String(uploaded.URL),
-			DirectPath:    proto.String(uploaded.DirectPath),
-			MediaKey:      uploaded.MediaKey,
-			Mimetype:      proto.String(http.DetectContentType(filedata)),
-			FileEncSHA256: uploaded.FileEncSHA256,
-			FileSHA256:    uploaded.FileSHA256,
-			FileLength:    proto.Uint64(uint64(len(filedata))),
-			JPEGThumbnail: thumbnailBytes,
-		}}
-
-		if t.ContextInfo.StanzaID != nil {
-			if msg.ImageMessage.ContextInfo == nil {
-				msg.ImageMessage.ContextInfo = &waE2E.ContextInfo{
-					StanzaID:      proto.String(*t.ContextInfo.StanzaID),
-					Participant:   proto.String(*t.ContextInfo.Participant),
-					QuotedMessage: &waE2E.Message{Conversation: proto.String("")},
-				}
-			}
+		imageMsg, err := uploadImageMessage(clientManager.GetWhatsmeowClient(userid), filedata, t.Caption)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+			return
 		}
 
-		if t.ContextInfo.MentionedJID != nil {
-			msg.ImageMessage.ContextInfo.MentionedJID = t.ContextInfo.MentionedJID
-		}
+		msg := &waE2E.Message{ImageMessage: imageMsg}
+
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
 
 		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
 
 		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
@@ -1026,11 +1116,14 @@ func (s *server) SendImage() http.HandlerFunc {
 func (s *server) SendSticker() http.HandlerFunc {
 
 	type stickerStruct struct {
-		Phone        string
-		Sticker      string
-		Id           string
-		PngThumbnail []byte
-		ContextInfo  waE2E.ContextInfo
+		Phone         string
+		Sticker       string
+		Id            string
+		PngThumbnail  []byte
+		PackName      string
+		PackPublisher string
+		Emojis        []string
+		ContextInfo   waE2E.ContextInfo
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1079,19 +1172,62 @@ func (s *server) SendSticker() http.HandlerFunc {
 		var uploaded whatsmeow.UploadResponse
 		var filedata []byte
 
+		var animated bool
+
 		if t.Sticker[0:4] == "data" {
 			var dataURL, err = dataurl.DecodeString(t.Sticker)
 			if err != nil {
 				s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
 				return
-			} else {
-				filedata = dataURL.Data
-				uploaded, err = clientManager.GetWhatsmeowClient(userid).Upload(context.Background(), filedata, whatsmeow.MediaImage)
+			}
+			filedata = dataURL.Data
+
+			// Normalize ordinary images into 512x512 WebP stickers
+			switch http.DetectContentType(filedata) {
+			case "image/webp":
+				// WebP is sent as is when it already fits, static files are converted otherwise.
+				// ffmpeg cannot decode animated WebP, so those have to be valid stickers already
+				animated = isAnimatedWebP(filedata)
+				if !validStickerWebP(filedata, animated) {
+					if animated {
+						s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Animated WebP stickers must be %dx%d and up to %d bytes", stickerSize, stickerSize, stickerMaxAnimatedSize)))
+						return
+					}
+					filedata, err = convertToSticker(filedata, false)
+				}
+			case "image/png", "image/jpeg":
+				filedata, err = convertToSticker(filedata, false)
+			case "image/gif":
+				animated = isAnimatedGIF(filedata)
+				filedata, err = convertToSticker(filedata, animated)
+			default:
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Sticker must be a webp, png, jpeg or gif image"))
+				return
+			}
+			if err != nil {
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not convert sticker: %v", err)))
+				return
+			}
+
+			if t.PackName != "" || t.PackPublisher != "" || len(t.Emojis) > 0 {
+				meta := stickerMetadata{
+					PackID:    stickerPackID(t.PackName, t.PackPublisher),
+					PackName:  t.PackName,
+					Publisher: t.PackPublisher,
+					Emojis:    t.Emojis,
+				}
+				filedata, err = setStickerMetadata(filedata, meta)
 				if err != nil {
-					s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to upload file: %v", err)))
+					s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not set sticker metadata: %v", err)))
 					return
 				}
 			}
+
+			uploaded, err = clientManager.GetWhatsmeowClient(userid).Upload(context.Background(), filedata, whatsmeow.MediaImage)
+			if err != nil {
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to upload file: %v", err)))
+				return
+			}
 		} else {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Data should start with \"data:mime/type;base64,\""))
 			return
@@ -1101,32 +1237,28 @@ func (s *server) SendSticker() http.HandlerFunc {
 			URL:           proto.String(uploaded.URL),
 			DirectPath:    proto.String(uploaded.DirectPath),
 			MediaKey:      uploaded.MediaKey,
-			Mimetype:      proto.String(http.DetectContentType(filedata)),
+			Mimetype:      proto.String("image/webp"),
 			FileEncSHA256: uploaded.FileEncSHA256,
 			FileSHA256:    uploaded.FileSHA256,
 			FileLength:    proto.Uint64(uint64(len(filedata))),
 			PngThumbnail:  t.PngThumbnail,
+			IsAnimated:    proto.Bool(animated),
 		}}
 
-		if t.ContextInfo.StanzaID != nil {
-			msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{
-				StanzaID:      proto.String(*t.ContextInfo.StanzaID),
-				Participant:   proto.String(*t.ContextInfo.Participant),
-				QuotedMessage: &waE2E.Message{Conversation: proto.String("")},
-			}
-		}
-		if t.ContextInfo.MentionedJID != nil {
-			if msg.ExtendedTextMessage.ContextInfo == nil {
-				msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
-			}
-			msg.ExtendedTextMessage.ContextInfo.MentionedJID = t.ContextInfo.MentionedJID
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
 		}
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
 
 		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
 
 		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
@@ -1149,6 +1281,10 @@ func (s *server) SendVideo() http.HandlerFunc {
 		Caption       string
 		Id            string
 		JPEGThumbnail []byte
+		GifPlayback   bool
+		PTV           bool
+		MentionAll    bool
+		HideMentions  bool
 		ContextInfo   waE2E.ContextInfo
 	}
 
@@ -1182,6 +1318,11 @@ func (s *server) SendVideo() http.HandlerFunc {
 			return
 		}
 
+		if t.GifPlayback && t.PTV {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("GifPlayback and PTV cannot be used together"))
+			return
+		}
+
 		recipient, err := validateMessageFields(t.Phone, t.ContextInfo.StanzaID, t.ContextInfo.Participant)
 		if err != nil {
 			log.Error().Msg(fmt.Sprintf("%s", err))
@@ -1195,7 +1336,18 @@ func (s *server) SendVideo() http.HandlerFunc {
 			msgid = t.Id
 		}
 
-		var uploaded whatsmeow.UploadResponse
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		contextInfo, t.Caption, err = addMentions(clientManager.GetWhatsmeowClient(userid), contextInfo, recipient, t.Caption, t.MentionAll, t.HideMentions)
+		if err != nil {
+			s.Respond(w, r, mentionErrorStatus(err), err)
+			return
+		}
+
 		var filedata []byte
 
 		if t.Video[0:4] == "data" {
@@ -1203,50 +1355,40 @@ func (s *server) SendVideo() http.HandlerFunc {
 			if err != nil {
 				s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
 				return
-			} else {
-				filedata = dataURL.Data
-				uploaded, err = clientManager.GetWhatsmeowClient(userid).Upload(context.Background(), filedata, whatsmeow.MediaVideo)
-				if err != nil {
-					s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to upload file: %v", err)))
-					return
-				}
 			}
+			filedata = dataURL.Data
 		} else {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Data should start with \"data:mime/type;base64,\""))
 			return
 		}
 
-		msg := &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
-			Caption:       proto.String(t.Caption),
-			URL:           proto.String(uploaded.URL),
-			DirectPath:    proto.String(uploaded.DirectPath),
-			MediaKey:      uploaded.MediaKey,
-			Mimetype:      proto.String(http.DetectContentType(filedata)),
-			FileEncSHA256: uploaded.FileEncSHA256,
-			FileSHA256:    uploaded.FileSHA256,
-			FileLength:    proto.Uint64(uint64(len(filedata))),
-			JPEGThumbnail: t.JPEGThumbnail,
-		}}
-
-		if t.ContextInfo.StanzaID != nil {
-			msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{
-				StanzaID:      proto.String(*t.ContextInfo.StanzaID),
-				Participant:   proto.String(*t.ContextInfo.Participant),
-				QuotedMessage: &waE2E.Message{Conversation: proto.String("")},
-			}
+		video, err := uploadVideoMessage(clientManager.GetWhatsmeowClient(userid), filedata, t.Caption, t.JPEGThumbnail)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+			return
 		}
-		if t.ContextInfo.MentionedJID != nil {
-			if msg.ExtendedTextMessage.ContextInfo == nil {
-				msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
-			}
-			msg.ExtendedTextMessage.ContextInfo.MentionedJID = t.ContextInfo.MentionedJID
+		if t.GifPlayback {
+			video.GifPlayback = proto.Bool(true)
+		}
+
+		var msg *waE2E.Message
+		if t.PTV {
+			// Round video notes carry no caption
+			video.Caption = nil
+			msg = &waE2E.Message{PtvMessage: video}
+		} else {
+			msg = &waE2E.Message{VideoMessage: video}
 		}
 
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
+
 		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
 
 		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
@@ -1260,17 +1402,33 @@ func (s *server) SendVideo() http.HandlerFunc {
 	}
 }
 
-// Sends Contact
-func (s *server) SendContact() http.HandlerFunc {
+// Most items an album can have, and how many of them are uploaded at the same time
+const (
+	maxAlbumItems          = 30
+	albumUploadConcurrency = 4
+)
 
-	type contactStruct struct {
+// Sends multiple images/videos grouped in an album
+func (s *server) SendAlbum() http.HandlerFunc {
+
+	type albumItemStruct struct {
+		Image   string
+		Video   string
+		Caption string
+	}
+
+	type albumStruct struct {
 		Phone       string
+		Items       []albumItemStruct
 		Id          string
-		Name        string
-		Vcard       string
 		ContextInfo waE2E.ContextInfo
 	}
 
+	type sentStruct struct {
+		Id        string
+		Timestamp time.Time
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		txtid := r.Context().Value("userinfo").(Values).Get("Id")
@@ -1281,26 +1439,26 @@ func (s *server) SendContact() http.HandlerFunc {
 			return
 		}
 
-		msgid := ""
-		var resp whatsmeow.SendResponse
-
 		decoder := json.NewDecoder(r.Body)
-		var t contactStruct
+		var t albumStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
+
 		if t.Phone == "" {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
 			return
 		}
-		if t.Name == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name in Payload"))
+
+		if len(t.Items) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Items in Payload"))
 			return
 		}
-		if t.Vcard == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Vcard in Payload"))
+
+		if len(t.Items) > maxAlbumItems {
+			s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Albums cannot have more than %d items", maxAlbumItems)))
 			return
 		}
 
@@ -1311,39 +1469,160 @@ func (s *server) SendContact() http.HandlerFunc {
 			return
 		}
 
-		if t.Id == "" {
-			msgid = whatsmeow.GenerateMessageID()
-		} else {
-			msgid = t.Id
+		// Like the phone does, the reply goes on the first item only, while mentions in
+		// each caption are set on the item they belong to
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
 		}
-
-		msg := &waE2E.Message{ContactMessage: &waE2E.ContactMessage{
-			DisplayName: &t.Name,
-			Vcard:       &t.Vcard,
-		}}
-
-		if t.ContextInfo.StanzaID != nil {
-			msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{
-				StanzaID:      proto.String(*t.ContextInfo.StanzaID),
-				Participant:   proto.String(*t.ContextInfo.Participant),
-				QuotedMessage: &waE2E.Message{Conversation: proto.String("")},
+		contextInfos := make([]*waE2E.ContextInfo, len(t.Items))
+		for i := range t.Items {
+			var itemContext *waE2E.ContextInfo
+			if i == 0 {
+				itemContext = contextInfo
+			}
+			contextInfos[i], t.Items[i].Caption, err = addMentions(clientManager.GetWhatsmeowClient(userid), itemContext, recipient, t.Items[i].Caption, false, false)
+			if err != nil {
+				s.Respond(w, r, mentionErrorStatus(err), err)
+				return
 			}
 		}
-		if t.ContextInfo.MentionedJID != nil {
-			if msg.ExtendedTextMessage.ContextInfo == nil {
-				msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
+
+		// Decode everything up front so a bad item fails the request before any upload
+		filedata := make([][]byte, len(t.Items))
+		for i, item := range t.Items {
+			var data string
+			switch {
+			case item.Image != "" && item.Video == "":
+				data = item.Image
+			case item.Video != "" && item.Image == "":
+				data = item.Video
+			default:
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Item %d must have either Image or Video", i)))
+				return
+			}
+			if !strings.HasPrefix(data, "data:") {
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Item %d data should start with \"data:mime/type;base64,\"", i)))
+				return
 			}
-			msg.ExtendedTextMessage.ContextInfo.MentionedJID = t.ContextInfo.MentionedJID
+			dataURL, err := dataurl.DecodeString(data)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Could not decode base64 encoded data from item %d", i)))
+				return
+			}
+			filedata[i] = dataURL.Data
+		}
+
+		client := clientManager.GetWhatsmeowClient(userid)
+
+		// Upload concurrently, keeping the original order of the items
+		messages := make([]*waE2E.Message, len(t.Items))
+		uploadErrors := make([]error, len(t.Items))
+		semaphore := make(chan struct{}, albumUploadConcurrency)
+		var wg sync.WaitGroup
+		for i, item := range t.Items {
+			wg.Add(1)
+			go func(i int, item albumItemStruct) {
+				defer wg.Done()
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				if item.Image != "" {
+					imageMsg, err := uploadImageMessage(client, filedata[i], item.Caption)
+					uploadErrors[i] = err
+					messages[i] = &waE2E.Message{ImageMessage: imageMsg}
+				} else {
+					videoMsg, err := uploadVideoMessage(client, filedata[i], item.Caption, nil)
+					uploadErrors[i] = err
+					messages[i] = &waE2E.Message{VideoMessage: videoMsg}
+				}
+			}(i, item)
 		}
+		wg.Wait()
 
-		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
-		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
-			return
+		for i, err := range uploadErrors {
+			if err != nil {
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Item %d: %v", i, err)))
+				return
+			}
 		}
 
-		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
-		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
+		albumid := ""
+		var sent []sentStruct
+
+		// A single item is sent as a regular message, more than one is grouped under an album parent
+		if len(messages) > 1 {
+			if t.Id == "" {
+				albumid = client.GenerateMessageID()
+			} else {
+				albumid = t.Id
+			}
+
+			album := &waE2E.AlbumMessage{}
+			for _, item := range t.Items {
+				if item.Image != "" {
+					album.ExpectedImageCount = proto.Uint32(album.GetExpectedImageCount() + 1)
+				} else {
+					album.ExpectedVideoCount = proto.Uint32(album.GetExpectedVideoCount() + 1)
+				}
+			}
+
+			_, err = client.SendMessage(context.Background(), recipient, &waE2E.Message{AlbumMessage: album}, whatsmeow.SendRequestExtra{ID: albumid})
+			if err != nil {
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending album: %v", err)))
+				return
+			}
+
+			for _, msg := range messages {
+				msg.MessageContextInfo = &waE2E.MessageContextInfo{
+					MessageAssociation: &waE2E.MessageAssociation{
+						AssociationType: waE2E.MessageAssociation_MEDIA_ALBUM.Enum(),
+						ParentMessageKey: &waCommon.MessageKey{
+							RemoteJID: proto.String(recipient.String()),
+							FromMe:    proto.Bool(true),
+							ID:        proto.String(albumid),
+						},
+					},
+				}
+			}
+		}
+
+		for i, msg := range messages {
+			setContextInfo(msg, contextInfos[i])
+			applyExpiration(client, userid, recipient, msg)
+			msgid := client.GenerateMessageID()
+			if albumid == "" && t.Id != "" {
+				msgid = t.Id
+			}
+			resp, err := client.SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
+			if err != nil {
+				if albumid == "" && len(sent) == 0 {
+					s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending item %d: %v", i, err)))
+					return
+				}
+				// Part of the album is already in the chat, return what was sent so it can be
+				// revoked or completed
+				log.Error().Str("error", fmt.Sprintf("%v", err)).Str("id", albumid).Int("item", i).Msg("Album partially sent")
+				response := map[string]interface{}{"Details": "Partially sent", "Error": fmt.Sprintf("Error sending item %d: %v", i, err), "Id": albumid, "Messages": sent}
+				responseJson, err := json.Marshal(response)
+				if err != nil {
+					s.Respond(w, r, http.StatusInternalServerError, err)
+				} else {
+					s.Respond(w, r, http.StatusInternalServerError, string(responseJson))
+				}
+				return
+			}
+			rememberMessage(userid, msgid, recipient, *client.Store.ID, msg)
+			sent = append(sent, sentStruct{Id: msgid, Timestamp: resp.Timestamp})
+		}
+
+		if albumid == "" {
+			albumid = sent[0].Id
+		}
+
+		log.Info().Str("id", albumid).Int("items", len(sent)).Msg("Album sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": sent[0].Timestamp, "Id": albumid, "Messages": sent}
 		responseJson, err := json.Marshal(response)
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
@@ -1354,16 +1633,15 @@ func (s *server) SendContact() http.HandlerFunc {
 	}
 }
 
-// Sends location
-func (s *server) SendLocation() http.HandlerFunc {
+// Posts a text status with optional background colour, text colour and font
+func (s *server) SendStatusText() http.HandlerFunc {
 
-	type locationStruct struct {
-		Phone       string
-		Id          string
-		Name        string
-		Latitude    float64
-		Longitude   float64
-		ContextInfo waE2E.ContextInfo
+	type statusTextStruct struct {
+		Body            string
+		BackgroundColor string
+		TextColor       string
+		Font            string
+		Id              string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1376,69 +1654,59 @@ func (s *server) SendLocation() http.HandlerFunc {
 			return
 		}
 
-		msgid := ""
-		var resp whatsmeow.SendResponse
-
 		decoder := json.NewDecoder(r.Body)
-		var t locationStruct
+		var t statusTextStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
-		if t.Phone == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
-			return
-		}
-		if t.Latitude == 0 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Latitude in Payload"))
-			return
-		}
-		if t.Longitude == 0 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Longitude in Payload"))
-			return
-		}
 
-		recipient, err := validateMessageFields(t.Phone, t.ContextInfo.StanzaID, t.ContextInfo.Participant)
-		if err != nil {
-			log.Error().Msg(fmt.Sprintf("%s", err))
-			s.Respond(w, r, http.StatusBadRequest, err)
+		if t.Body == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Body in Payload"))
 			return
 		}
 
-		if t.Id == "" {
-			msgid = whatsmeow.GenerateMessageID()
-		} else {
-			msgid = t.Id
+		text := &waE2E.ExtendedTextMessage{Text: &t.Body}
+		if t.BackgroundColor != "" {
+			color, err := parseStatusColor(t.BackgroundColor)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid BackgroundColor: %v", err)))
+				return
+			}
+			text.BackgroundArgb = proto.Uint32(color)
 		}
-
-		msg := &waE2E.Message{LocationMessage: &waE2E.LocationMessage{
-			DegreesLatitude:  &t.Latitude,
-			DegreesLongitude: &t.Longitude,
-			Name:             &t.Name,
-		}}
-
-		if t.ContextInfo.StanzaID != nil {
-			msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{
-				StanzaID:      proto.String(*t.ContextInfo.StanzaID),
-				Participant:   proto.String(*t.ContextInfo.Participant),
-				QuotedMessage: &waE2E.Message{Conversation: proto.String("")},
+		if t.TextColor != "" {
+			color, err := parseStatusColor(t.TextColor)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid TextColor: %v", err)))
+				return
 			}
+			text.TextArgb = proto.Uint32(color)
 		}
-		if t.ContextInfo.MentionedJID != nil {
-			if msg.ExtendedTextMessage.ContextInfo == nil {
-				msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
+		if t.Font != "" {
+			font, err := parseStatusFont(t.Font)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid Font: %v", err)))
+				return
 			}
-			msg.ExtendedTextMessage.ContextInfo.MentionedJID = t.ContextInfo.MentionedJID
+			text.Font = font.Enum()
 		}
 
-		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
+		client := clientManager.GetWhatsmeowClient(userid)
+
+		msgid := t.Id
+		if msgid == "" {
+			msgid = client.GenerateMessageID()
+		}
+
+		resp, err := sendStatus(client, userid, msgid, &waE2E.Message{ExtendedTextMessage: text})
 		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending status: %v", err)))
 			return
 		}
 
-		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Status sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
 		responseJson, err := json.Marshal(response)
 		if err != nil {
@@ -1450,18 +1718,12 @@ func (s *server) SendLocation() http.HandlerFunc {
 	}
 }
 
-// Sends Buttons (not implemented, does not work)
-
-func (s *server) SendButtons() http.HandlerFunc {
+// Posts an image status
+func (s *server) SendStatusImage() http.HandlerFunc {
 
-	type buttonStruct struct {
-		ButtonId   string
-		ButtonText string
-	}
-	type textStruct struct {
-		Phone   string
-		Title   string
-		Buttons []buttonStruct
+	type statusImageStruct struct {
+		Image   string
+		Caption string
 		Id      string
 	}
 
@@ -1475,76 +1737,49 @@ func (s *server) SendButtons() http.HandlerFunc {
 			return
 		}
 
-		msgid := ""
-		var resp whatsmeow.SendResponse
-
 		decoder := json.NewDecoder(r.Body)
-		var t textStruct
+		var t statusImageStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		if t.Phone == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
-			return
-		}
-
-		if t.Title == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Title in Payload"))
+		if t.Image == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Image in Payload"))
 			return
 		}
 
-		if len(t.Buttons) < 1 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Buttons in Payload"))
-			return
-		}
-		if len(t.Buttons) > 3 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("buttons cant more than 3"))
+		if !strings.HasPrefix(t.Image, "data:image") {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Image data should start with \"data:image/png;base64,\""))
 			return
 		}
-
-		recipient, ok := parseJID(t.Phone)
-		if !ok {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+		dataURL, err := dataurl.DecodeString(t.Image)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
 			return
 		}
 
-		if t.Id == "" {
-			msgid = whatsmeow.GenerateMessageID()
-		} else {
-			msgid = t.Id
-		}
-
-		var buttons []*waE2E.ButtonsMessage_Button
+		client := clientManager.GetWhatsmeowClient(userid)
 
-		for _, item := range t.Buttons {
-			buttons = append(buttons, &waE2E.ButtonsMessage_Button{
-				ButtonID:       proto.String(item.ButtonId),
-				ButtonText:     &waE2E.ButtonsMessage_Button_ButtonText{DisplayText: proto.String(item.ButtonText)},
-				Type:           waE2E.ButtonsMessage_Button_RESPONSE.Enum(),
-				NativeFlowInfo: &waE2E.ButtonsMessage_Button_NativeFlowInfo{},
-			})
+		imageMsg, err := uploadImageMessage(client, dataURL.Data, t.Caption)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+			return
 		}
 
-		msg2 := &waE2E.ButtonsMessage{
-			ContentText: proto.String(t.Title),
-			HeaderType:  waE2E.ButtonsMessage_EMPTY.Enum(),
-			Buttons:     buttons,
+		msgid := t.Id
+		if msgid == "" {
+			msgid = client.GenerateMessageID()
 		}
 
-		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, &waE2E.Message{ViewOnceMessage: &waE2E.FutureProofMessage{
-			Message: &waE2E.Message{
-				ButtonsMessage: msg2,
-			},
-		}}, whatsmeow.SendRequestExtra{ID: msgid})
+		resp, err := sendStatus(client, userid, msgid, &waE2E.Message{ImageMessage: imageMsg})
 		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending status: %v", err)))
 			return
 		}
 
-		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Status sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
 		responseJson, err := json.Marshal(response)
 		if err != nil {
@@ -1556,29 +1791,14 @@ func (s *server) SendButtons() http.HandlerFunc {
 	}
 }
 
-// SendList
-// https://github.com/tulir/whatsmeow/issues/305
-func (s *server) SendList() http.HandlerFunc {
-
-	type rowsStruct struct {
-		RowId       string
-		Title       string
-		Description string
-	}
-
-	type sectionsStruct struct {
-		Title string
-		Rows  []rowsStruct
-	}
+// Posts a video status
+func (s *server) SendStatusVideo() http.HandlerFunc {
 
-	type listStruct struct {
-		Phone       string
-		Title       string
-		Description string
-		ButtonText  string
-		FooterText  string
-		Sections    []sectionsStruct
-		Id          string
+	type statusVideoStruct struct {
+		Video         string
+		Caption       string
+		Id            string
+		JPEGThumbnail []byte
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1587,103 +1807,142 @@ func (s *server) SendList() http.HandlerFunc {
 		userid, _ := strconv.Atoi(txtid)
 
 		if clientManager.GetWhatsmeowClient(userid) == nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New("no session"))
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
 			return
 		}
 
-		msgid := ""
-		var resp whatsmeow.SendResponse
-
 		decoder := json.NewDecoder(r.Body)
-		var t listStruct
+		var t statusVideoStruct
 		err := decoder.Decode(&t)
-		marshal, _ := json.Marshal(t)
-		fmt.Println(string(marshal))
 		if err != nil {
-			fmt.Println(err)
-			s.Respond(w, r, http.StatusBadRequest, errors.New("could not decode Payload"))
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		if t.Phone == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Phone in Payload"))
+		if t.Video == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Video in Payload"))
 			return
 		}
 
-		if t.Title == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Title in Payload"))
+		if !strings.HasPrefix(t.Video, "data:") {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Data should start with \"data:mime/type;base64,\""))
 			return
 		}
-
-		if t.Description == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Description in Payload"))
+		dataURL, err := dataurl.DecodeString(t.Video)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
 			return
 		}
 
-		if t.ButtonText == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("missing ButtonText in Payload"))
+		client := clientManager.GetWhatsmeowClient(userid)
+
+		video, err := uploadVideoMessage(client, dataURL.Data, t.Caption, t.JPEGThumbnail)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		if len(t.Sections) < 1 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Sections in Payload"))
-			return
+		msgid := t.Id
+		if msgid == "" {
+			msgid = client.GenerateMessageID()
 		}
-		recipient, ok := parseJID(t.Phone)
-		if !ok {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("could not parse Phone"))
+
+		resp, err := sendStatus(client, userid, msgid, &waE2E.Message{VideoMessage: video})
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending status: %v", err)))
 			return
 		}
 
-		if t.Id == "" {
-			msgid = whatsmeow.GenerateMessageID()
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Status sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
-			msgid = t.Id
+			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
+		return
+	}
+}
 
-		var sections []*waE2E.ListMessage_Section
+// Sends Contact
+func (s *server) SendContact() http.HandlerFunc {
 
-		for _, item := range t.Sections {
-			var rows []*waE2E.ListMessage_Row
-			id := 1
-			for _, row := range item.Rows {
-				var idtext string
-				if row.RowId == "" {
-					idtext = strconv.Itoa(id)
-				} else {
-					idtext = row.RowId
-				}
-				rows = append(rows, &waE2E.ListMessage_Row{
-					RowID:       proto.String(idtext),
-					Title:       proto.String(row.Title),
-					Description: proto.String(row.Description),
-				})
+	// A single contact goes in the top level fields, several in Contacts
+	type contactStruct struct {
+		Phone string
+		Id    string
+		contactCard
+		Contacts    []contactCard
+		ContextInfo waE2E.ContextInfo
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		msgid := ""
+		var resp whatsmeow.SendResponse
+
+		decoder := json.NewDecoder(r.Body)
+		var t contactStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+		cards := t.Contacts
+		if len(cards) == 0 {
+			if t.Name == "" {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name in Payload"))
+				return
 			}
+			cards = []contactCard{t.contactCard}
+		}
 
-			sections = append(sections, &waE2E.ListMessage_Section{
-				Title: proto.String(item.Title),
-				Rows:  rows,
-			})
+		recipient, err := validateMessageFields(t.Phone, t.ContextInfo.StanzaID, t.ContextInfo.Participant)
+		if err != nil {
+			log.Error().Msg(fmt.Sprintf("%s", err))
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
 		}
-		msg1 := &waE2E.ListMessage{
-			Title:       proto.String(t.Title),
-			Description: proto.String(t.Description),
-			ButtonText:  proto.String(t.ButtonText),
-			ListType:    waE2E.ListMessage_SINGLE_SELECT.Enum(),
-			Sections:    sections,
-			FooterText:  proto.String(t.FooterText),
+
+		if t.Id == "" {
+			msgid = whatsmeow.GenerateMessageID()
+		} else {
+			msgid = t.Id
 		}
 
-		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, &waE2E.Message{
-			ViewOnceMessage: &waE2E.FutureProofMessage{
-				Message: &waE2E.Message{
-					ListMessage: msg1,
-				},
-			}}, whatsmeow.SendRequestExtra{ID: msgid})
+		msg, err := buildContactsMessage(cards)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
+
+		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
 
 		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
@@ -1697,13 +1956,17 @@ func (s *server) SendList() http.HandlerFunc {
 	}
 }
 
-// Sends a regular text message
-func (s *server) SendMessage() http.HandlerFunc {
+// Sends location
+func (s *server) SendLocation() http.HandlerFunc {
 
-	type textStruct struct {
+	type locationStruct struct {
 		Phone       string
-		Body        string
 		Id          string
+		Name        string
+		Address     string
+		Url         string
+		Latitude    float64
+		Longitude   float64
 		ContextInfo waE2E.ContextInfo
 	}
 
@@ -1721,20 +1984,22 @@ func (s *server) SendMessage() http.HandlerFunc {
 		var resp whatsmeow.SendResponse
 
 		decoder := json.NewDecoder(r.Body)
-		var t textStruct
+		var t locationStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
-
 		if t.Phone == "" {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
 			return
 		}
-
-		if t.Body == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Body in Payload"))
+		if t.Latitude == 0 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Latitude in Payload"))
+			return
+		}
+		if t.Longitude == 0 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Longitude in Payload"))
 			return
 		}
 
@@ -1746,37 +2011,37 @@ func (s *server) SendMessage() http.HandlerFunc {
 		}
 
 		if t.Id == "" {
-			//msgid = whatsmeow.GenerateMessageID()
-			msgid = clientManager.GetWhatsmeowClient(userid).GenerateMessageID()
+			msgid = whatsmeow.GenerateMessageID()
 		} else {
 			msgid = t.Id
 		}
 
-		msg := &waE2E.Message{
-			ExtendedTextMessage: &waE2E.ExtendedTextMessage{
-				Text: &t.Body,
-			},
+		msg := &waE2E.Message{LocationMessage: &waE2E.LocationMessage{
+			DegreesLatitude:  &t.Latitude,
+			DegreesLongitude: &t.Longitude,
+			Name:             &t.Name,
+		}}
+		if t.Address != "" {
+			msg.LocationMessage.Address = proto.String(t.Address)
 		}
-
-		if t.ContextInfo.StanzaID != nil {
-			msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{
-				StanzaID:      proto.String(*t.ContextInfo.StanzaID),
-				Participant:   proto.String(*t.ContextInfo.Participant),
-				QuotedMessage: &waE2E.Message{Conversation: proto.String("")},
-			}
+		if t.Url != "" {
+			msg.LocationMessage.URL = proto.String(t.Url)
 		}
-		if t.ContextInfo.MentionedJID != nil {
-			if msg.ExtendedTextMessage.ContextInfo == nil {
-				msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
-			}
-			msg.ExtendedTextMessage.ContextInfo.MentionedJID = t.ContextInfo.MentionedJID
+
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
 		}
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
 
 		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
 
 		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
 		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
@@ -1786,17 +2051,23 @@ func (s *server) SendMessage() http.HandlerFunc {
 		} else {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
-
 		return
 	}
 }
 
-// Delete message
-func (s *server) DeleteMessage() http.HandlerFunc {
-
-	type textStruct struct {
-		Phone string
-		Id    string
+// Starts sharing live location in a chat
+func (s *server) StartLiveLocation() http.HandlerFunc {
+
+	type liveLocationStruct struct {
+		Phone     string
+		Id        string
+		Latitude  float64
+		Longitude float64
+		Accuracy  uint32
+		Speed     float32
+		Heading   uint32
+		Caption   string
+		Duration  string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1809,28 +2080,34 @@ func (s *server) DeleteMessage() http.HandlerFunc {
 			return
 		}
 
-		msgid := ""
-		var resp whatsmeow.SendResponse
-
 		decoder := json.NewDecoder(r.Body)
-		var t textStruct
+		var t liveLocationStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
-
 		if t.Phone == "" {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
 			return
 		}
-
-		if t.Id == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Id in Payload"))
+		if t.Latitude == 0 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Latitude in Payload"))
+			return
+		}
+		if t.Longitude == 0 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Longitude in Payload"))
 			return
 		}
 
-		msgid = t.Id
+		duration := defaultLiveLocationDuration
+		if t.Duration != "" {
+			duration, err = time.ParseDuration(t.Duration)
+			if err != nil || duration <= 0 || duration > maxLiveLocationDuration {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid Duration, use a duration up to 8h like 15m or 1h"))
+				return
+			}
+		}
 
 		recipient, ok := parseJID(t.Phone)
 		if !ok {
@@ -1838,43 +2115,55 @@ func (s *server) DeleteMessage() http.HandlerFunc {
 			return
 		}
 
-		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, clientManager.GetWhatsmeowClient(userid).BuildRevoke(recipient, types.EmptyJID, msgid))
+		msgid := t.Id
+		if msgid == "" {
+			msgid = whatsmeow.GenerateMessageID()
+		}
+
+		now := time.Now()
+		share := &liveLocationShare{
+			Id:             msgid,
+			Chat:           recipient,
+			Caption:        t.Caption,
+			StartedAt:      now,
+			ExpiresAt:      now.Add(duration),
+			SequenceNumber: 1,
+			Position:       livePosition{Latitude: t.Latitude, Longitude: t.Longitude, Accuracy: t.Accuracy, Speed: t.Speed, Heading: t.Heading},
+		}
+		msg := buildLiveLocation(*share)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
+
+		resp, err := clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
+		liveLocationStore.Start(userid, share)
 
-		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message deleted")
-		response := map[string]interface{}{"Details": "Deleted", "Timestamp": resp.Timestamp, "Id": msgid}
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Str("chat", recipient.String()).Msg("Live location started")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid, "ExpiresAt": share.ExpiresAt}
 		responseJson, err := json.Marshal(response)
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
-
 		return
 	}
 }
 
-/*
-// Sends a Template message
-func (s *server) SendTemplate() http.HandlerFunc {
-
-	type buttonStruct struct {
-		DisplayText string
-		Id          string
-		Url         string
-		PhoneNumber string
-		Type        string
-	}
-
-	type templateStruct struct {
-		Phone   string
-		Content string
-		Footer  string
-		Id      string
-		Buttons []buttonStruct
+// Sends a new position for the live location shared in a chat
+func (s *server) UpdateLiveLocation() http.HandlerFunc {
+
+	type liveLocationStruct struct {
+		Phone          string
+		Latitude       float64
+		Longitude      float64
+		Accuracy       uint32
+		Speed          float32
+		Heading        uint32
+		SequenceNumber int64
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1887,35 +2176,23 @@ func (s *server) SendTemplate() http.HandlerFunc {
 			return
 		}
 
-		msgid := ""
-		var resp whatsmeow.SendResponse
-//var ts time.Time
-
 		decoder := json.NewDecoder(r.Body)
-		var t templateStruct
+		var t liveLocationStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
-
 		if t.Phone == "" {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
 			return
 		}
-
-		if t.Content == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Content in Payload"))
-			return
-		}
-
-		if t.Footer == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Footer in Payload"))
+		if t.Latitude == 0 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Latitude in Payload"))
 			return
 		}
-
-		if len(t.Buttons) < 1 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Buttons in Payload"))
+		if t.Longitude == 0 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Longitude in Payload"))
 			return
 		}
 
@@ -1925,87 +2202,22 @@ func (s *server) SendTemplate() http.HandlerFunc {
 			return
 		}
 
-		if t.Id == "" {
-			msgid = whatsmeow.GenerateMessageID()
-		} else {
-			msgid = t.Id
-		}
-
-		var buttons []*waE2E.HydratedTemplateButton
-
-		id := 1
-		for _, item := range t.Buttons {
-			switch item.Type {
-			case "quickreply":
-				var idtext string
-				text := item.DisplayText
-				if item.Id == "" {
-					idtext = strconv.Itoa(id)
-				} else {
-					idtext = item.Id
-				}
-				buttons = append(buttons, &waE2E.HydratedTemplateButton{
-					HydratedButton: &waE2E.HydratedTemplateButton_QuickReplyButton{
-						QuickReplyButton: &waE2E.HydratedQuickReplyButton{
-							DisplayText: &text,
-							Id:          proto.String(idtext),
-						},
-					},
-				})
-			case "url":
-				text := item.DisplayText
-				url := item.Url
-				buttons = append(buttons, &waE2E.HydratedTemplateButton{
-					HydratedButton: &waE2E.HydratedTemplateButton_UrlButton{
-						UrlButton: &waE2E.HydratedURLButton{
-							DisplayText: &text,
-							Url:         &url,
-						},
-					},
-				})
-			case "call":
-				text := item.DisplayText
-				phonenumber := item.PhoneNumber
-				buttons = append(buttons, &waE2E.HydratedTemplateButton{
-					HydratedButton: &waE2E.HydratedTemplateButton_CallButton{
-						CallButton: &waE2E.HydratedCallButton{
-							DisplayText: &text,
-							PhoneNumber: &phonenumber,
-						},
-					},
-				})
-			default:
-				text := item.DisplayText
-				buttons = append(buttons, &waE2E.HydratedTemplateButton{
-					HydratedButton: &waE2E.HydratedTemplateButton_QuickReplyButton{
-						QuickReplyButton: &waE2E.HydratedQuickReplyButton{
-							DisplayText: &text,
-							Id:          proto.String(string(id)),
-						},
-					},
-				})
-			}
-			id++
+		share, ok := liveLocationStore.Advance(userid, recipient, t.SequenceNumber, livePosition{Latitude: t.Latitude, Longitude: t.Longitude, Accuracy: t.Accuracy, Speed: t.Speed, Heading: t.Heading})
+		if !ok {
+			s.Respond(w, r, http.StatusNotFound, errors.New("No live location being shared in this chat"))
+			return
 		}
 
-		msg := &waE2E.Message{TemplateMessage: &waE2E.TemplateMessage{
-			HydratedTemplate: &waE2E.HydratedFourRowTemplate{
-				HydratedContentText: proto.String(t.Content),
-				HydratedFooterText:  proto.String(t.Footer),
-				HydratedButtons:     buttons,
-				TemplateId:          proto.String("1"),
-			},
-		},
-		}
+		msg := buildLiveLocationUpdate(clientManager.GetWhatsmeowClient(userid), userid, share)
 
-		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(),recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
+		resp, err := clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg)
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
 
-		log.Info().Str("timestamp", fmt.Sprintf("%d", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
-		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
+		log.Debug().Str("id", share.Id).Int64("sequence", share.SequenceNumber).Str("chat", recipient.String()).Msg("Live location updated")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": share.Id, "SequenceNumber": share.SequenceNumber}
 		responseJson, err := json.Marshal(response)
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
@@ -2015,23 +2227,16 @@ func (s *server) SendTemplate() http.HandlerFunc {
 		return
 	}
 }
-*/
-// checks if users/phones are on Whatsapp
-func (s *server) CheckUser() http.HandlerFunc {
-
-	type checkUserStruct struct {
-		Phone []string
-	}
 
-	type User struct {
-		Query        string
-		IsInWhatsapp bool
-		JID          string
-		VerifiedName string
-	}
+// Stops sharing live location in a chat. WhatsApp has no message to end a share early, so
+// a last update is sent with its time offset at the end of the share, and no further
+// updates are accepted
+func (s *server) StopLiveLocation() http.HandlerFunc {
 
-	type UserCollection struct {
-		Users []User
+	type liveLocationStruct struct {
+		Phone     string
+		Latitude  float64
+		Longitude float64
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2045,35 +2250,50 @@ func (s *server) CheckUser() http.HandlerFunc {
 		}
 
 		decoder := json.NewDecoder(r.Body)
-		var t checkUserStruct
+		var t liveLocationStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
-
-		if len(t.Phone) < 1 {
+		if t.Phone == "" {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
 			return
 		}
 
-		resp, err := clientManager.GetWhatsmeowClient(userid).IsOnWhatsApp(t.Phone)
-		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to check if users are on WhatsApp: %s", err)))
+		recipient, ok := parseJID(t.Phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
 			return
 		}
 
-		uc := new(UserCollection)
-		for _, item := range resp {
-			if item.VerifiedName != nil {
-				var msg = User{Query: item.Query, IsInWhatsapp: item.IsIn, JID: fmt.Sprintf("%s", item.JID), VerifiedName: item.VerifiedName.Details.GetVerifiedName()}
-				uc.Users = append(uc.Users, msg)
-			} else {
-				var msg = User{Query: item.Query, IsInWhatsapp: item.IsIn, JID: fmt.Sprintf("%s", item.JID), VerifiedName: ""}
-				uc.Users = append(uc.Users, msg)
-			}
+		share, ok := liveLocationStore.Get(userid, recipient)
+		if !ok {
+			s.Respond(w, r, http.StatusNotFound, errors.New("No live location being shared in this chat"))
+			return
 		}
-		responseJson, err := json.Marshal(uc)
+
+		// The share ends with a last update, at the final position when one is given
+		pos := share.Position
+		if t.Latitude != 0 && t.Longitude != 0 {
+			pos = livePosition{Latitude: t.Latitude, Longitude: t.Longitude}
+		}
+		share, ok = liveLocationStore.Advance(userid, recipient, 0, pos)
+		if !ok {
+			s.Respond(w, r, http.StatusNotFound, errors.New("No live location being shared in this chat"))
+			return
+		}
+		msg := buildLiveLocationStop(clientManager.GetWhatsmeowClient(userid), userid, share)
+		_, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+		liveLocationStore.Stop(userid, recipient)
+
+		log.Info().Str("chat", recipient.String()).Msg("Live location stopped")
+		response := map[string]interface{}{"Details": "Live location stopped"}
+		responseJson, err := json.Marshal(response)
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
@@ -2083,15 +2303,20 @@ func (s *server) CheckUser() http.HandlerFunc {
 	}
 }
 
-// Gets user information
-func (s *server) GetUser() http.HandlerFunc {
+// Sends Buttons (not implemented, does not work)
 
-	type checkUserStruct struct {
-		Phone []string
-	}
+func (s *server) SendButtons() http.HandlerFunc {
 
-	type UserCollection struct {
-		Users map[types.JID]types.UserInfo
+	type buttonStruct struct {
+		ButtonId   string
+		ButtonText string
+	}
+	type textStruct struct {
+		Phone       string
+		Title       string
+		Buttons     []buttonStruct
+		Id          string
+		ContextInfo waE2E.ContextInfo
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2104,98 +2329,86 @@ func (s *server) GetUser() http.HandlerFunc {
 			return
 		}
 
+		msgid := ""
+		var resp whatsmeow.SendResponse
+
 		decoder := json.NewDecoder(r.Body)
-		var t checkUserStruct
+		var t textStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		if len(t.Phone) < 1 {
+		if t.Phone == "" {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
 			return
 		}
 
-		var jids []types.JID
-		for _, arg := range t.Phone {
-			jid, err := types.ParseJID(arg)
-			if err != nil {
-				return
-			}
-			jids = append(jids, jid)
+		if t.Title == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Title in Payload"))
+			return
 		}
-		resp, err := clientManager.GetWhatsmeowClient(userid).GetUserInfo(jids)
 
-		if err != nil {
-			msg := fmt.Sprintf("Failed to get user info: %v", err)
-			log.Error().Msg(msg)
-			s.Respond(w, r, http.StatusInternalServerError, msg)
+		if len(t.Buttons) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Buttons in Payload"))
 			return
 		}
-
-		uc := new(UserCollection)
-		uc.Users = make(map[types.JID]types.UserInfo)
-
-		for jid, info := range resp {
-			uc.Users[jid] = info
+		if len(t.Buttons) > 3 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("buttons cant more than 3"))
+			return
 		}
 
-		responseJson, err := json.Marshal(uc)
+		recipient, err := validateMessageFields(t.Phone, t.ContextInfo.StanzaID, t.ContextInfo.Participant)
 		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, err)
-		} else {
-			s.Respond(w, r, http.StatusOK, string(responseJson))
+			log.Error().Msg(fmt.Sprintf("%s", err))
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
 		}
-		return
-	}
-}
 
-func (s *server) SendPresence() http.HandlerFunc {
-
-	type PresenceRequest struct {
-		Type string `json:"type" form:"type"`
-	}
-
-	return func(w http.ResponseWriter, r *http.Request) {
+		if t.Id == "" {
+			msgid = whatsmeow.GenerateMessageID()
+		} else {
+			msgid = t.Id
+		}
 
-		txtid := r.Context().Value("userinfo").(Values).Get("Id")
-		userid, _ := strconv.Atoi(txtid)
+		var buttons []*waE2E.ButtonsMessage_Button
 
-		if clientManager.GetWhatsmeowClient(userid) == nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
-			return
+		for _, item := range t.Buttons {
+			buttons = append(buttons, &waE2E.ButtonsMessage_Button{
+				ButtonID:       proto.String(item.ButtonId),
+				ButtonText:     &waE2E.ButtonsMessage_Button_ButtonText{DisplayText: proto.String(item.ButtonText)},
+				Type:           waE2E.ButtonsMessage_Button_RESPONSE.Enum(),
+				NativeFlowInfo: &waE2E.ButtonsMessage_Button_NativeFlowInfo{},
+			})
 		}
 
-		decoder := json.NewDecoder(r.Body)
-		var pre PresenceRequest
-		err := decoder.Decode(&pre)
-		if err != nil {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
-			return
+		msg2 := &waE2E.ButtonsMessage{
+			ContentText: proto.String(t.Title),
+			HeaderType:  waE2E.ButtonsMessage_EMPTY.Enum(),
+			Buttons:     buttons,
 		}
+		inner := &waE2E.Message{ButtonsMessage: msg2}
 
-		var presence types.Presence
-
-		switch pre.Type {
-		case "available":
-			presence = types.PresenceAvailable
-		case "unavailable":
-			presence = types.PresenceUnavailable
-		default:
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid presence type. Allowed values: 'available', 'unavailable'"))
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
 			return
 		}
+		setContextInfo(inner, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, inner)
 
-		log.Info().Str("presence", pre.Type).Msg("Your global presence status")
-
-		err = clientManager.GetWhatsmeowClient(userid).SendPresence(presence)
+		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, &waE2E.Message{ViewOnceMessage: &waE2E.FutureProofMessage{
+			Message: inner,
+		}}, whatsmeow.SendRequestExtra{ID: msgid})
 		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New("Failure sending presence to Whatsapp servers"))
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
 			return
 		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, inner)
 
-		response := map[string]interface{}{"Details": "Presence set successfuly"}
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
 		responseJson, err := json.Marshal(response)
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
@@ -2203,16 +2416,33 @@ func (s *server) SendPresence() http.HandlerFunc {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
 		return
-
 	}
 }
 
-// Gets avatar info for user
-func (s *server) GetAvatar() http.HandlerFunc {
+// SendList
+// https://github.com/tulir/whatsmeow/issues/305
+func (s *server) SendList() http.HandlerFunc {
 
-	type getAvatarStruct struct {
-		Phone   string
-		Preview bool
+	type rowsStruct struct {
+		RowId       string
+		Title       string
+		Description string
+	}
+
+	type sectionsStruct struct {
+		Title string
+		Rows  []rowsStruct
+	}
+
+	type listStruct struct {
+		Phone       string
+		Title       string
+		Description string
+		ButtonText  string
+		FooterText  string
+		Sections    []sectionsStruct
+		Id          string
+		ContextInfo waE2E.ContextInfo
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2221,81 +2451,3583 @@ func (s *server) GetAvatar() http.HandlerFunc {
 		userid, _ := strconv.Atoi(txtid)
 
 		if clientManager.GetWhatsmeowClient(userid) == nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("no session"))
 			return
 		}
 
+		msgid := ""
+		var resp whatsmeow.SendResponse
+
 		decoder := json.NewDecoder(r.Body)
-		var t getAvatarStruct
+		var t listStruct
 		err := decoder.Decode(&t)
+		marshal, _ := json.Marshal(&t)
+		fmt.Println(string(marshal))
 		if err != nil {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			fmt.Println(err)
+			s.Respond(w, r, http.StatusBadRequest, errors.New("could not decode Payload"))
 			return
 		}
 
-		if len(t.Phone) < 1 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Phone in Payload"))
 			return
 		}
 
-		jid, ok := parseJID(t.Phone)
-		if !ok {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+		if t.Title == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Title in Payload"))
 			return
 		}
 
-		var pic *types.ProfilePictureInfo
-
-		existingID := ""
-		pic, err = clientManager.GetWhatsmeowClient(userid).GetProfilePictureInfo(jid, &whatsmeow.GetProfilePictureParams{
-			Preview:    t.Preview,
-			ExistingID: existingID,
-		})
-		if err != nil {
-			msg := fmt.Sprintf("Failed to get avatar: %v", err)
-			log.Error().Msg(msg)
-			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+		if t.Description == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Description in Payload"))
 			return
 		}
 
-		if pic == nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New("No avatar found"))
+		if t.ButtonText == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("missing ButtonText in Payload"))
 			return
 		}
 
-		log.Info().Str("id", pic.ID).Str("url", pic.URL).Msg("Got avatar")
-
-		responseJson, err := json.Marshal(pic)
-		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, err)
-		} else {
-			s.Respond(w, r, http.StatusOK, string(responseJson))
-		}
-		return
-	}
-}
-
-// Gets all contacts
-func (s *server) GetContacts() http.HandlerFunc {
-
-	return func(w http.ResponseWriter, r *http.Request) {
-
-		txtid := r.Context().Value("userinfo").(Values).Get("Id")
-		userid, _ := strconv.Atoi(txtid)
-
-		if clientManager.GetWhatsmeowClient(userid) == nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+		if len(t.Sections) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("missing Sections in Payload"))
 			return
 		}
-
-		result := map[types.JID]types.ContactInfo{}
-		result, err := clientManager.GetWhatsmeowClient(userid).Store.Contacts.GetAllContacts()
+		recipient, err := validateMessageFields(t.Phone, t.ContextInfo.StanzaID, t.ContextInfo.Participant)
 		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, err)
+			log.Error().Msg(fmt.Sprintf("%s", err))
+			s.Respond(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		responseJson, err := json.Marshal(result)
+		if t.Id == "" {
+			msgid = whatsmeow.GenerateMessageID()
+		} else {
+			msgid = t.Id
+		}
+
+		var sections []*waE2E.ListMessage_Section
+
+		for _, item := range t.Sections {
+			var rows []*waE2E.ListMessage_Row
+			id := 1
+			for _, row := range item.Rows {
+				var idtext string
+				if row.RowId == "" {
+					idtext = strconv.Itoa(id)
+				} else {
+					idtext = row.RowId
+				}
+				rows = append(rows, &waE2E.ListMessage_Row{
+					RowID:       proto.String(idtext),
+					Title:       proto.String(row.Title),
+					Description: proto.String(row.Description),
+				})
+			}
+
+			sections = append(sections, &waE2E.ListMessage_Section{
+				Title: proto.String(item.Title),
+				Rows:  rows,
+			})
+		}
+		msg1 := &waE2E.ListMessage{
+			Title:       proto.String(t.Title),
+			Description: proto.String(t.Description),
+			ButtonText:  proto.String(t.ButtonText),
+			ListType:    waE2E.ListMessage_SINGLE_SELECT.Enum(),
+			Sections:    sections,
+			FooterText:  proto.String(t.FooterText),
+		}
+		inner := &waE2E.Message{ListMessage: msg1}
+
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+		setContextInfo(inner, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, inner)
+
+		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, &waE2E.Message{
+			ViewOnceMessage: &waE2E.FutureProofMessage{
+				Message: inner,
+			}}, whatsmeow.SendRequestExtra{ID: msgid})
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, inner)
+
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Sends a regular text message
+func (s *server) SendMessage() http.HandlerFunc {
+
+	type linkPreviewStruct struct {
+		Title         string
+		Description   string
+		JPEGThumbnail []byte
+	}
+
+	type textStruct struct {
+		Phone            string
+		Body             string
+		Id               string
+		LinkPreview      *linkPreviewStruct
+		FetchLinkPreview bool
+		MentionAll       bool
+		HideMentions     bool
+		ContextInfo      waE2E.ContextInfo
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		msgid := ""
+		var resp whatsmeow.SendResponse
+
+		decoder := json.NewDecoder(r.Body)
+		var t textStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		if t.Body == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Body in Payload"))
+			return
+		}
+
+		recipient, err := validateMessageFields(t.Phone, t.ContextInfo.StanzaID, t.ContextInfo.Participant)
+		if err != nil {
+			log.Error().Msg(fmt.Sprintf("%s", err))
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if t.Id == "" {
+			//msgid = whatsmeow.GenerateMessageID()
+			msgid = clientManager.GetWhatsmeowClient(userid).GenerateMessageID()
+		} else {
+			msgid = t.Id
+		}
+
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		contextInfo, t.Body, err = addMentions(clientManager.GetWhatsmeowClient(userid), contextInfo, recipient, t.Body, t.MentionAll, t.HideMentions)
+		if err != nil {
+			s.Respond(w, r, mentionErrorStatus(err), err)
+			return
+		}
+
+		msg := &waE2E.Message{
+			ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text: &t.Body,
+			},
+		}
+
+		// Preview fields supplied by the caller take precedence over fetching the page,
+		// which is only done on request as it delays the send
+		if link := findFirstURL(t.Body); link != "" && (t.LinkPreview != nil || t.FetchLinkPreview) {
+			var preview *linkPreview
+			if t.LinkPreview != nil {
+				preview = &linkPreview{
+					MatchedText:   link,
+					Title:         t.LinkPreview.Title,
+					Description:   t.LinkPreview.Description,
+					JPEGThumbnail: t.LinkPreview.JPEGThumbnail,
+				}
+			} else {
+				preview, err = fetchLinkPreview(link)
+				if err != nil {
+					log.Warn().Err(err).Str("url", link).Msg("Could not build link preview")
+				}
+			}
+			if preview != nil {
+				msg.ExtendedTextMessage.MatchedText = proto.String(preview.MatchedText)
+				msg.ExtendedTextMessage.Title = proto.String(preview.Title)
+				msg.ExtendedTextMessage.Description = proto.String(preview.Description)
+				msg.ExtendedTextMessage.JPEGThumbnail = preview.JPEGThumbnail
+				msg.ExtendedTextMessage.PreviewType = waE2E.ExtendedTextMessage_NONE.Enum()
+			}
+		}
+
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
+
+		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+		rememberMessage(userid, msgid, recipient, *clientManager.GetWhatsmeowClient(userid).Store.ID, msg)
+
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Delete message
+func (s *server) DeleteMessage() http.HandlerFunc {
+
+	type textStruct struct {
+		Phone string
+		Id    string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		msgid := ""
+		var resp whatsmeow.SendResponse
+
+		decoder := json.NewDecoder(r.Body)
+		var t textStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		if t.Id == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Id in Payload"))
+			return
+		}
+
+		msgid = t.Id
+
+		recipient, ok := parseJID(t.Phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			return
+		}
+
+		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, clientManager.GetWhatsmeowClient(userid).BuildRevoke(recipient, types.EmptyJID, msgid))
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message deleted")
+		response := map[string]interface{}{"Details": "Deleted", "Timestamp": resp.Timestamp, "Id": msgid}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+/*
+// Sends a Template message
+func (s *server) SendTemplate() http.HandlerFunc {
+
+	type buttonStruct struct {
+		DisplayText string
+		Id          string
+		Url         string
+		PhoneNumber string
+		Type        string
+	}
+
+	type templateStruct struct {
+		Phone       string
+		Content     string
+		Footer      string
+		Id          string
+		Buttons     []buttonStruct
+		ContextInfo waE2E.ContextInfo
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		msgid := ""
+		var resp whatsmeow.SendResponse
+//var ts time.Time
+
+		decoder := json.NewDecoder(r.Body)
+		var t templateStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		if t.Content == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Content in Payload"))
+			return
+		}
+
+		if t.Footer == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Footer in Payload"))
+			return
+		}
+
+		if len(t.Buttons) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Buttons in Payload"))
+			return
+		}
+
+		recipient, ok := parseJID(t.Phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			return
+		}
+
+		if t.Id == "" {
+			msgid = whatsmeow.GenerateMessageID()
+		} else {
+			msgid = t.Id
+		}
+
+		var buttons []*waE2E.HydratedTemplateButton
+
+		id := 1
+		for _, item := range t.Buttons {
+			switch item.Type {
+			case "quickreply":
+				var idtext string
+				text := item.DisplayText
+				if item.Id == "" {
+					idtext = strconv.Itoa(id)
+				} else {
+					idtext = item.Id
+				}
+				buttons = append(buttons, &waE2E.HydratedTemplateButton{
+					HydratedButton: &waE2E.HydratedTemplateButton_QuickReplyButton{
+						QuickReplyButton: &waE2E.HydratedQuickReplyButton{
+							DisplayText: &text,
+							Id:          proto.String(idtext),
+						},
+					},
+				})
+			case "url":
+				text := item.DisplayText
+				url := item.Url
+				buttons = append(buttons, &waE2E.HydratedTemplateButton{
+					HydratedButton: &waE2E.HydratedTemplateButton_UrlButton{
+						UrlButton: &waE2E.HydratedURLButton{
+							DisplayText: &text,
+							Url:         &url,
+						},
+					},
+				})
+			case "call":
+				text := item.DisplayText
+				phonenumber := item.PhoneNumber
+				buttons = append(buttons, &waE2E.HydratedTemplateButton{
+					HydratedButton: &waE2E.HydratedTemplateButton_CallButton{
+						CallButton: &waE2E.HydratedCallButton{
+							DisplayText: &text,
+							PhoneNumber: &phonenumber,
+						},
+					},
+				})
+			default:
+				text := item.DisplayText
+				buttons = append(buttons, &waE2E.HydratedTemplateButton{
+					HydratedButton: &waE2E.HydratedTemplateButton_QuickReplyButton{
+						QuickReplyButton: &waE2E.HydratedQuickReplyButton{
+							DisplayText: &text,
+							Id:          proto.String(string(id)),
+						},
+					},
+				})
+			}
+			id++
+		}
+
+		msg := &waE2E.Message{TemplateMessage: &waE2E.TemplateMessage{
+			HydratedTemplate: &waE2E.HydratedFourRowTemplate{
+				HydratedContentText: proto.String(t.Content),
+				HydratedFooterText:  proto.String(t.Footer),
+				HydratedButtons:     buttons,
+				TemplateId:          proto.String("1"),
+			},
+		},
+		}
+
+		contextInfo, err := buildContextInfo(userid, &t.ContextInfo)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+		setContextInfo(msg, contextInfo)
+		applyExpiration(clientManager.GetWhatsmeowClient(userid), userid, recipient, msg)
+
+		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(),recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+
+		log.Info().Str("timestamp", fmt.Sprintf("%d", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+*/
+// checks if users/phones are on Whatsapp
+func (s *server) CheckUser() http.HandlerFunc {
+
+	type checkUserStruct struct {
+		Phone []string
+	}
+
+	type User struct {
+		Query        string
+		IsInWhatsapp bool
+		JID          string
+		VerifiedName string
+		Error        string `json:",omitempty"`
+	}
+
+	type UserCollection struct {
+		Users []User
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t checkUserStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if len(t.Phone) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		var numbers []string
+		normalized := make(map[string]string)
+		invalid := make(map[string]string)
+		for _, phone := range t.Phone {
+			number, err := normalizePhone(phone, *defaultCountry)
+			if err != nil {
+				invalid[phone] = err.Error()
+				continue
+			}
+			normalized[phone] = number
+			numbers = append(numbers, number)
+		}
+
+		checks, err := checkNumbers(clientManager.GetWhatsmeowClient(userid), numbers)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to check if users are on WhatsApp: %s", err)))
+			return
+		}
+
+		uc := new(UserCollection)
+		for _, phone := range t.Phone {
+			number, ok := normalized[phone]
+			if !ok {
+				uc.Users = append(uc.Users, User{Query: phone, Error: invalid[phone]})
+				continue
+			}
+			check := checks[number]
+			uc.Users = append(uc.Users, User{Query: phone, IsInWhatsapp: check.IsInWhatsapp, JID: check.JID, VerifiedName: check.VerifiedName})
+		}
+		responseJson, err := json.Marshal(uc)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Checks a large list of free-form phone numbers, normalizing them to E.164
+func (s *server) CheckUsersBulk() http.HandlerFunc {
+
+	type checkUsersBulkStruct struct {
+		Phone   []string
+		Country string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t checkUsersBulkStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if len(t.Phone) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		if len(t.Phone) > maxBulkCheckNumbers {
+			s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Too many numbers, at most %d can be checked at once", maxBulkCheckNumbers)))
+			return
+		}
+
+		country := strings.TrimPrefix(t.Country, "+")
+		if country == "" {
+			country = *defaultCountry
+		} else if !validCountryCode(country) {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Country must be a country calling code made of 1 to 3 digits"))
+			return
+		}
+
+		results := make([]numberCheck, len(t.Phone))
+		var numbers []string
+		for i, phone := range t.Phone {
+			results[i].Query = phone
+			number, err := normalizePhone(phone, country)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Number = number
+			numbers = append(numbers, number)
+		}
+
+		checks, err := checkNumbers(clientManager.GetWhatsmeowClient(userid), numbers)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Failed to check if users are on WhatsApp: %s", err)))
+			return
+		}
+
+		registered, invalid := 0, 0
+		for i := range results {
+			if results[i].Error != "" {
+				invalid++
+				continue
+			}
+			check := checks[results[i].Number]
+			check.Query = results[i].Query
+			results[i] = check
+			if check.IsInWhatsapp {
+				registered++
+			}
+		}
+
+		response := map[string]interface{}{"Total": len(results), "Registered": registered, "Invalid": invalid, "Users": results}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Gets user information
+func (s *server) GetUser() http.HandlerFunc {
+
+	type checkUserStruct struct {
+		Phone []string
+	}
+
+	type UserCollection struct {
+		Users map[types.JID]types.UserInfo
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t checkUserStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if len(t.Phone) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		var jids []types.JID
+		for _, arg := range t.Phone {
+			jid, err := types.ParseJID(arg)
+			if err != nil {
+				return
+			}
+			jids = append(jids, jid)
+		}
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetUserInfo(jids)
+
+		if err != nil {
+			msg := fmt.Sprintf("Failed to get user info: %v", err)
+			log.Error().Msg(msg)
+			s.Respond(w, r, http.StatusInternalServerError, msg)
+			return
+		}
+
+		uc := new(UserCollection)
+		uc.Users = make(map[types.JID]types.UserInfo)
+
+		for jid, info := range resp {
+			uc.Users[jid] = info
+		}
+
+		responseJson, err := json.Marshal(uc)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+func (s *server) SendPresence() http.HandlerFunc {
+
+	type PresenceRequest struct {
+		Type string `json:"type" form:"type"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var pre PresenceRequest
+		err := decoder.Decode(&pre)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		var presence types.Presence
+
+		switch pre.Type {
+		case "available":
+			presence = types.PresenceAvailable
+		case "unavailable":
+			presence = types.PresenceUnavailable
+		default:
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid presence type. Allowed values: 'available', 'unavailable'"))
+			return
+		}
+
+		log.Info().Str("presence", pre.Type).Msg("Your global presence status")
+
+		err = clientManager.GetWhatsmeowClient(userid).SendPresence(presence)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("Failure sending presence to Whatsapp servers"))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Presence set successfuly"}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+
+	}
+}
+
+// Gets avatar info for user
+func (s *server) GetAvatar() http.HandlerFunc {
+
+	type getAvatarStruct struct {
+		Phone   string
+		Preview bool
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t getAvatarStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if len(t.Phone) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		jid, ok := parseJID(t.Phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			return
+		}
+
+		var pic *types.ProfilePictureInfo
+
+		existingID := ""
+		pic, err = clientManager.GetWhatsmeowClient(userid).GetProfilePictureInfo(jid, &whatsmeow.GetProfilePictureParams{
+			Preview:    t.Preview,
+			ExistingID: existingID,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("Failed to get avatar: %v", err)
+			log.Error().Msg(msg)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		if pic == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No avatar found"))
+			return
+		}
+
+		log.Info().Str("id", pic.ID).Str("url", pic.URL).Msg("Got avatar")
+
+		responseJson, err := json.Marshal(pic)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Gets own profile: push name, about text and profile picture
+func (s *server) GetProfile() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		client := clientManager.GetWhatsmeowClient(userid)
+		if client.Store.ID == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("Not logged in"))
+			return
+		}
+		own := client.Store.ID.ToNonAD()
+
+		response := map[string]interface{}{"JID": own.String(), "PushName": client.Store.PushName, "About": "", "PictureID": "", "PictureURL": ""}
+
+		info, err := client.GetUserInfo([]types.JID{own})
+		if err != nil {
+			log.Warn().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get own about text")
+		} else if item, ok := info[own]; ok {
+			response["About"] = item.Status
+		}
+
+		pic, err := client.GetProfilePictureInfo(own, &whatsmeow.GetProfilePictureParams{})
+		if err != nil {
+			log.Warn().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get own profile picture")
+		} else if pic != nil {
+			response["PictureID"] = pic.ID
+			response["PictureURL"] = pic.URL
+		}
+
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Sets own push name, the name shown to contacts that do not have us saved
+func (s *server) SetProfileName() http.HandlerFunc {
+
+	type setProfileNameStruct struct {
+		Name string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setProfileNameStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Name == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name in Payload"))
+			return
+		}
+
+		client := clientManager.GetWhatsmeowClient(userid)
+		err = client.SendAppState(appstate.BuildSettingPushName(t.Name))
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set push name")
+			msg := fmt.Sprintf("Failed to set push name: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		// The push name travels with our presence, announce it again so contacts see the change
+		client.Store.PushName = t.Name
+		err = client.SendPresence(types.PresenceAvailable)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to send available presence")
+		}
+
+		response := map[string]interface{}{"Details": "Push name set successfully", "PushName": t.Name}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Sets own about (status) text
+func (s *server) SetProfileAbout() http.HandlerFunc {
+
+	type setProfileAboutStruct struct {
+		About string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setProfileAboutStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.About == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing About in Payload"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SetStatusMessage(t.About)
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set about text")
+			msg := fmt.Sprintf("Failed to set about text: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "About text set successfully"}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Sets own profile picture
+func (s *server) SetProfilePicture() http.HandlerFunc {
+
+	type setProfilePictureStruct struct {
+		Image string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setProfilePictureStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Image == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Image in Payload"))
+			return
+		}
+
+		var filedata []byte
+
+		if strings.HasPrefix(t.Image, "data:image") {
+			var dataURL, err = dataurl.DecodeString(t.Image)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
+				return
+			}
+			filedata = dataURL.Data
+		} else {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Image data should start with \"data:image/jpeg;base64,\""))
+			return
+		}
+
+		picture, err := profilePicture(filedata)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Could not process image: %v", err)))
+			return
+		}
+
+		// An empty JID targets our own profile
+		pictureID, err := clientManager.GetWhatsmeowClient(userid).SetGroupPhoto(types.EmptyJID, picture)
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set profile picture")
+			msg := fmt.Sprintf("Failed to set profile picture: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Profile picture set successfully", "PictureID": pictureID}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Removes own profile picture
+func (s *server) RemoveProfilePicture() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		_, err := clientManager.GetWhatsmeowClient(userid).SetGroupPhoto(types.EmptyJID, nil)
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to remove profile picture")
+			msg := fmt.Sprintf("Failed to remove profile picture: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Profile picture removed successfully"}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Values accepted by WhatsApp for each privacy setting
+var privacySettingValues = map[types.PrivacySettingType][]types.PrivacySetting{
+	types.PrivacySettingTypeGroupAdd:     {types.PrivacySettingAll, types.PrivacySettingContacts, types.PrivacySettingContactBlacklist, types.PrivacySettingNone},
+	types.PrivacySettingTypeLastSeen:     {types.PrivacySettingAll, types.PrivacySettingContacts, types.PrivacySettingContactBlacklist, types.PrivacySettingNone},
+	types.PrivacySettingTypeStatus:       {types.PrivacySettingAll, types.PrivacySettingContacts, types.PrivacySettingContactBlacklist, types.PrivacySettingNone},
+	types.PrivacySettingTypeProfile:      {types.PrivacySettingAll, types.PrivacySettingContacts, types.PrivacySettingContactBlacklist, types.PrivacySettingNone},
+	types.PrivacySettingTypeReadReceipts: {types.PrivacySettingAll, types.PrivacySettingNone},
+	types.PrivacySettingTypeOnline:       {types.PrivacySettingAll, types.PrivacySettingMatchLastSeen},
+	types.PrivacySettingTypeCallAdd:      {types.PrivacySettingAll, types.PrivacySettingKnown},
+}
+
+// Gets privacy settings
+func (s *server) GetPrivacySettings() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		settings, err := clientManager.GetWhatsmeowClient(userid).TryFetchPrivacySettings(false)
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get privacy settings")
+			msg := fmt.Sprintf("Failed to get privacy settings: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		responseJson, err := json.Marshal(settings)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Sets privacy settings, only the settings present in the payload are changed
+func (s *server) SetPrivacySettings() http.HandlerFunc {
+
+	type privacySettingsStruct struct {
+		GroupAdd     string
+		LastSeen     string
+		Status       string
+		Profile      string
+		ReadReceipts string
+		Online       string
+		CallAdd      string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t privacySettingsStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		changes := []struct {
+			name  types.PrivacySettingType
+			field string
+			value string
+		}{
+			{types.PrivacySettingTypeGroupAdd, "GroupAdd", t.GroupAdd},
+			{types.PrivacySettingTypeLastSeen, "LastSeen", t.LastSeen},
+			{types.PrivacySettingTypeStatus, "Status", t.Status},
+			{types.PrivacySettingTypeProfile, "Profile", t.Profile},
+			{types.PrivacySettingTypeReadReceipts, "ReadReceipts", t.ReadReceipts},
+			{types.PrivacySettingTypeOnline, "Online", t.Online},
+			{types.PrivacySettingTypeCallAdd, "CallAdd", t.CallAdd},
+		}
+
+		// Validate everything first so a bad value does not leave settings half applied
+		pending := 0
+		for _, change := range changes {
+			if change.value == "" {
+				continue
+			}
+			allowed := privacySettingValues[change.name]
+			valid := false
+			for _, value := range allowed {
+				if string(value) == change.value {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				var names []string
+				for _, value := range allowed {
+					names = append(names, "'"+string(value)+"'")
+				}
+				msg := fmt.Sprintf("Invalid %s. Allowed values: %s", change.field, strings.Join(names, ", "))
+				s.Respond(w, r, http.StatusBadRequest, errors.New(msg))
+				return
+			}
+			pending++
+		}
+
+		if pending == 0 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("No privacy settings in Payload"))
+			return
+		}
+
+		var settings types.PrivacySettings
+		for _, change := range changes {
+			if change.value == "" {
+				continue
+			}
+			settings, err = clientManager.GetWhatsmeowClient(userid).SetPrivacySetting(change.name, types.PrivacySetting(change.value))
+			if err != nil {
+				log.Error().Str("error", fmt.Sprintf("%v", err)).Str("setting", change.field).Msg("Failed to set privacy setting")
+				msg := fmt.Sprintf("Failed to set %s privacy setting: %v", change.field, err)
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+				return
+			}
+		}
+
+		responseJson, err := json.Marshal(settings)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Sets the default disappearing messages timer used for new chats
+func (s *server) SetDefaultEphemeral() http.HandlerFunc {
+
+	type setDefaultEphemeralStruct struct {
+		Duration string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setDefaultEphemeralStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		timer, ok := parseDisappearingTimer(t.Duration)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid Duration. Allowed values: '24h', '7d', '90d', 'off'"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SetDefaultDisappearingTimer(timer)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set default disappearing timer")
+			msg := fmt.Sprintf("Failed to set default disappearing timer: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		// Kept so messages to new chats carry the timer
+		if err := saveDefaultEphemeral(s.db, userid, uint32(timer.Seconds())); err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to save default disappearing timer")
+		}
+
+		response := map[string]interface{}{"Details": "Default disappearing timer set successfully"}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Blocklist as returned by the blocklist endpoints
+type blocklistResponse struct {
+	DHash string
+	JIDs  []string
+}
+
+func newBlocklistResponse(blocklist *types.Blocklist) blocklistResponse {
+	response := blocklistResponse{DHash: blocklist.DHash, JIDs: []string{}}
+	for _, jid := range blocklist.JIDs {
+		response.JIDs = append(response.JIDs, jid.String())
+	}
+	return response
+}
+
+// Gets the list of blocked users
+func (s *server) GetBlocklist() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		blocklist, err := clientManager.GetWhatsmeowClient(userid).GetBlocklist()
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get blocklist")
+			msg := fmt.Sprintf("Failed to get blocklist: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		responseJson, err := json.Marshal(newBlocklistResponse(blocklist))
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Blocks users
+func (s *server) BlockUsers() http.HandlerFunc {
+	return s.updateBlocklist(events.BlocklistChangeActionBlock)
+}
+
+// Unblocks users
+func (s *server) UnblockUsers() http.HandlerFunc {
+	return s.updateBlocklist(events.BlocklistChangeActionUnblock)
+}
+
+func (s *server) updateBlocklist(action events.BlocklistChangeAction) http.HandlerFunc {
+
+	type updateBlocklistStruct struct {
+		Phone []string
+	}
+
+	type blockResult struct {
+		JID     string
+		Success bool
+		Error   string `json:",omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t updateBlocklistStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if len(t.Phone) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		jids, err := parseJIDList(t.Phone)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		var blocklist *types.Blocklist
+		results := []blockResult{}
+		for _, jid := range jids {
+			updated, err := clientManager.GetWhatsmeowClient(userid).UpdateBlocklist(jid, action)
+			if err != nil {
+				log.Error().Str("error", fmt.Sprintf("%v", err)).Str("jid", jid.String()).Str("action", string(action)).Msg("Failed to update blocklist")
+				results = append(results, blockResult{JID: jid.String(), Success: false, Error: err.Error()})
+				continue
+			}
+			blocklist = updated
+			results = append(results, blockResult{JID: jid.String(), Success: true})
+		}
+
+		response := map[string]interface{}{"Details": "Blocklist updated", "Results": results}
+		if blocklist != nil {
+			response["Blocklist"] = newBlocklistResponse(blocklist)
+		}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Subscribes to presence updates (online/offline and last seen) of a list of users
+func (s *server) SubscribePresence() http.HandlerFunc {
+
+	type subscribePresenceStruct struct {
+		Phone []string
+	}
+
+	type subscribeResult struct {
+		JID     string
+		Success bool
+		Error   string `json:",omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t subscribePresenceStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if len(t.Phone) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		jids, err := parseJIDList(t.Phone)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		results := []subscribeResult{}
+		for _, jid := range jids {
+			err := clientManager.GetWhatsmeowClient(userid).SubscribePresence(jid)
+			if err != nil {
+				log.Error().Str("error", fmt.Sprintf("%v", err)).Str("jid", jid.String()).Msg("Failed to subscribe to presence")
+				results = append(results, subscribeResult{JID: jid.String(), Success: false, Error: err.Error()})
+				continue
+			}
+			if err := savePresenceSubscription(s.db, userid, jid); err != nil {
+				log.Error().Err(err).Str("jid", jid.String()).Msg("Failed to save presence subscription")
+				results = append(results, subscribeResult{JID: jid.String(), Success: false, Error: "Subscribed, but could not be saved to be restored on reconnect"})
+				continue
+			}
+			results = append(results, subscribeResult{JID: jid.String(), Success: true})
+		}
+
+		response := map[string]interface{}{"Details": "Presence subscriptions updated", "Results": results}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Gets the last known presence of a user
+func (s *server) GetPresence() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		phone := r.URL.Query().Get("phone")
+		if phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing phone parameter"))
+			return
+		}
+
+		jid, ok := parseJID(phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			return
+		}
+
+		state := presenceStore.Get(userid, jid)
+		response := map[string]interface{}{
+			"JID":        jid.String(),
+			"Subscribed": presenceStore.IsSubscribed(userid, jid),
+			"State":      state.State,
+			"LastSeen":   state.LastSeen,
+			"UpdatedAt":  state.UpdatedAt,
+		}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Page size of /user/contacts when no limit is given, and the largest allowed
+const (
+	defaultContactsPageSize = 100
+	maxContactsPageSize     = 1000
+)
+
+// Contact as returned in the paginated contact list and by /user/contact
+type contactEntry struct {
+	JID          string
+	Found        bool
+	FirstName    string
+	FullName     string
+	PushName     string
+	BusinessName string
+}
+
+func newContactEntry(jid types.JID, info types.ContactInfo) contactEntry {
+	return contactEntry{
+		JID:          jid.String(),
+		Found:        info.Found,
+		FirstName:    info.FirstName,
+		FullName:     info.FullName,
+		PushName:     info.PushName,
+		BusinessName: info.BusinessName,
+	}
+}
+
+// Gets contacts. Without query parameters the whole contact store is returned as a map
+// keyed by JID. With page, limit, search, business or named the contacts are filtered
+// and returned as a sorted, paginated list
+func (s *server) GetContacts() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		result := map[types.JID]types.ContactInfo{}
+		result, err := clientManager.GetWhatsmeowClient(userid).Store.Contacts.GetAllContacts()
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		query := r.URL.Query()
+		if len(query) == 0 {
+			responseJson, err := json.Marshal(result)
+			if err != nil {
+				s.Respond(w, r, http.StatusInternalServerError, err)
+			} else {
+				s.Respond(w, r, http.StatusOK, string(responseJson))
+			}
+			return
+		}
+
+		page := 1
+		if query.Get("page") != "" {
+			page, err = strconv.Atoi(query.Get("page"))
+			if err != nil || page < 1 {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid page parameter"))
+				return
+			}
+		}
+
+		limit := defaultContactsPageSize
+		if query.Get("limit") != "" {
+			limit, err = strconv.Atoi(query.Get("limit"))
+			if err != nil || limit < 1 || limit > maxContactsPageSize {
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid limit parameter, must be between 1 and %d", maxContactsPageSize)))
+				return
+			}
+		}
+
+		search := strings.ToLower(strings.TrimSpace(query.Get("search")))
+		// Numbers can be searched with their usual formatting (+, spaces, dashes, parentheses),
+		// searches with anything else only match names
+		searchDigits := ""
+		if strings.Trim(search, "0123456789+-() ") == "" {
+			searchDigits = strings.Map(func(c rune) rune {
+				if c >= '0' && c <= '9' {
+					return c
+				}
+				return -1
+			}, search)
+		}
+		businessOnly := query.Get("business") == "true"
+		namedOnly := query.Get("named") == "true"
+
+		contacts := []contactEntry{}
+		for jid, info := range result {
+			if businessOnly && info.BusinessName == "" {
+				continue
+			}
+			if namedOnly && info.FirstName == "" && info.FullName == "" {
+				continue
+			}
+			if search != "" && (searchDigits == "" || !strings.Contains(jid.User, searchDigits)) &&
+				!strings.Contains(strings.ToLower(info.FirstName), search) &&
+				!strings.Contains(strings.ToLower(info.FullName), search) &&
+				!strings.Contains(strings.ToLower(info.PushName), search) &&
+				!strings.Contains(strings.ToLower(info.BusinessName), search) {
+				continue
+			}
+			contacts = append(contacts, newContactEntry(jid, info))
+		}
+		sort.Slice(contacts, func(i, j int) bool {
+			return contacts[i].JID < contacts[j].JID
+		})
+
+		total := len(contacts)
+		from := (page - 1) * limit
+		if from > total {
+			from = total
+		}
+		to := from + limit
+		if to > total {
+			to = total
+		}
+
+		response := map[string]interface{}{"Total": total, "Page": page, "Limit": limit, "Contacts": contacts[from:to]}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Gets a single contact
+func (s *server) GetContact() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		vars := mux.Vars(r)
+		jid, ok := parseJID(vars["jid"])
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse JID"))
+			return
+		}
+
+		info, err := clientManager.GetWhatsmeowClient(userid).Store.Contacts.GetContact(jid)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !info.Found {
+			s.Respond(w, r, http.StatusNotFound, errors.New("Contact not found"))
+			return
+		}
+
+		responseJson, err := json.Marshal(newContactEntry(jid, info))
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Sets Chat Presence (typing/paused/recording audio)
+func (s *server) ChatPresence() http.HandlerFunc {
+
+	type chatPresenceStruct struct {
+		Phone string
+		State string
+		Media types.ChatPresenceMedia
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t chatPresenceStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if len(t.Phone) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		if len(t.State) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing State in Payload"))
+			return
+		}
+
+		jid, ok := parseJID(t.Phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SendChatPresence(jid, types.ChatPresence(t.State), types.ChatPresenceMedia(t.Media))
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("Failure sending chat presence to Whatsapp servers"))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Chat presence set successfuly"}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Downloads Image and returns base64 representation
+func (s *server) DownloadImage() http.HandlerFunc {
+
+	type downloadImageStruct struct {
+		Url           string
+		DirectPath    string
+		MediaKey      []byte
+		Mimetype      string
+		FileEncSHA256 []byte
+		FileSHA256    []byte
+		FileLength    uint64
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		mimetype := ""
+		var imgdata []byte
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		// check/creates user directory for files
+		userDirectory := filepath.Join(s.exPath, "files", "user_"+txtid)
+		_, err := os.Stat(userDirectory)
+		if os.IsNotExist(err) {
+			errDir := os.MkdirAll(userDirectory, 0751)
+			if errDir != nil {
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create user directory (%s)", userDirectory)))
+				return
+			}
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t downloadImageStruct
+		err = decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		msg := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			URL:           proto.String(t.Url),
+			DirectPath:    proto.String(t.DirectPath),
+			MediaKey:      t.MediaKey,
+			Mimetype:      proto.String(t.Mimetype),
+			FileEncSHA256: t.FileEncSHA256,
+			FileSHA256:    t.FileSHA256,
+			FileLength:    &t.FileLength,
+		}}
+
+		img := msg.GetImageMessage()
+
+		if img != nil {
+			imgdata, err = clientManager.GetWhatsmeowClient(userid).Download(img)
+			if err != nil {
+				log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to download image")
+				msg := fmt.Sprintf("Failed to download image %v", err)
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+				return
+			}
+			mimetype = img.GetMimetype()
+		}
+
+		dataURL := dataurl.New(imgdata, mimetype)
+		response := map[string]interface{}{"Mimetype": mimetype, "Data": dataURL.String()}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Downloads Document and returns base64 representation
+func (s *server) DownloadDocument() http.HandlerFunc {
+
+	type downloadDocumentStruct struct {
+		Url           string
+		DirectPath    string
+		MediaKey      []byte
+		Mimetype      string
+		FileEncSHA256 []byte
+		FileSHA256    []byte
+		FileLength    uint64
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		mimetype := ""
+		var docdata []byte
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		// check/creates user directory for files
+		userDirectory := filepath.Join(s.exPath, "files", "user_"+txtid)
+		_, err := os.Stat(userDirectory)
+		if os.IsNotExist(err) {
+			errDir := os.MkdirAll(userDirectory, 0751)
+			if errDir != nil {
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create user directory (%s)", userDirectory)))
+				return
+			}
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t downloadDocumentStruct
+		err = decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		msg := &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+			URL:           proto.String(t.Url),
+			DirectPath:    proto.String(t.DirectPath),
+			MediaKey:      t.MediaKey,
+			Mimetype:      proto.String(t.Mimetype),
+			FileEncSHA256: t.FileEncSHA256,
+			FileSHA256:    t.FileSHA256,
+			FileLength:    &t.FileLength,
+		}}
+
+		doc := msg.GetDocumentMessage()
+
+		if doc != nil {
+			docdata, err = clientManager.GetWhatsmeowClient(userid).Download(doc)
+			if err != nil {
+				log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to download document")
+				msg := fmt.Sprintf("Failed to download document %v", err)
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+				return
+			}
+			mimetype = doc.GetMimetype()
+		}
+
+		dataURL := dataurl.New(docdata, mimetype)
+		response := map[string]interface{}{"Mimetype": mimetype, "Data": dataURL.String()}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Downloads Video and returns base64 representation
+func (s *server) DownloadVideo() http.HandlerFunc {
+
+	type downloadVideoStruct struct {
+		Url           string
+		DirectPath    string
+		MediaKey      []byte
+		Mimetype      string
+		FileEncSHA256 []byte
+		FileSHA256    []byte
+		FileLength    uint64
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		mimetype := ""
+		var docdata []byte
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		// check/creates user directory for files
+		userDirectory := filepath.Join(s.exPath, "files", "user_"+txtid)
+		_, err := os.Stat(userDirectory)
+		if os.IsNotExist(err) {
+			errDir := os.MkdirAll(userDirectory, 0751)
+			if errDir != nil {
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create user directory (%s)", userDirectory)))
+				return
+			}
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t downloadVideoStruct
+		err = decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		msg := &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+			URL:           proto.String(t.Url),
+			DirectPath:    proto.String(t.DirectPath),
+			MediaKey:      t.MediaKey,
+			Mimetype:      proto.String(t.Mimetype),
+			FileEncSHA256: t.FileEncSHA256,
+			FileSHA256:    t.FileSHA256,
+			FileLength:    &t.FileLength,
+		}}
+
+		doc := msg.GetVideoMessage()
+
+		if doc != nil {
+			docdata, err = clientManager.GetWhatsmeowClient(userid).Download(doc)
+			if err != nil {
+				log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to download video")
+				msg := fmt.Sprintf("Failed to download video %v", err)
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+				return
+			}
+			mimetype = doc.GetMimetype()
+		}
+
+		dataURL := dataurl.New(docdata, mimetype)
+		response := map[string]interface{}{"Mimetype": mimetype, "Data": dataURL.String()}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Downloads Audio and returns base64 representation
+func (s *server) DownloadAudio() http.HandlerFunc {
+
+	type downloadAudioStruct struct {
+		Url           string
+		DirectPath    string
+		MediaKey      []byte
+		Mimetype      string
+		FileEncSHA256 []byte
+		FileSHA256    []byte
+		FileLength    uint64
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		mimetype := ""
+		var docdata []byte
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		// check/creates user directory for files
+		userDirectory := filepath.Join(s.exPath, "files", "user_"+txtid)
+		_, err := os.Stat(userDirectory)
+		if os.IsNotExist(err) {
+			errDir := os.MkdirAll(userDirectory, 0751)
+			if errDir != nil {
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create user directory (%s)", userDirectory)))
+				return
+			}
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t downloadAudioStruct
+		err = decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		msg := &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
+			URL:           proto.String(t.Url),
+			DirectPath:    proto.String(t.DirectPath),
+			MediaKey:      t.MediaKey,
+			Mimetype:      proto.String(t.Mimetype),
+			FileEncSHA256: t.FileEncSHA256,
+			FileSHA256:    t.FileSHA256,
+			FileLength:    &t.FileLength,
+		}}
+
+		doc := msg.GetAudioMessage()
+
+		if doc != nil {
+			docdata, err = clientManager.GetWhatsmeowClient(userid).Download(doc)
+			if err != nil {
+				log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to download audio")
+				msg := fmt.Sprintf("Failed to download audio %v", err)
+				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+				return
+			}
+			mimetype = doc.GetMimetype()
+		}
+
+		dataURL := dataurl.New(docdata, mimetype)
+		response := map[string]interface{}{"Mimetype": mimetype, "Data": dataURL.String()}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// React
+func (s *server) React() http.HandlerFunc {
+
+	type textStruct struct {
+		Phone string
+		Body  string
+		Id    string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		msgid := ""
+		var resp whatsmeow.SendResponse
+
+		decoder := json.NewDecoder(r.Body)
+		var t textStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		if t.Body == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Body in Payload"))
+			return
+		}
+
+		recipient, ok := parseJID(t.Phone)
+		if !ok {
+			log.Error().Msg(fmt.Sprintf("%s", err))
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		if t.Id == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Id in Payload"))
+			return
+		} else {
+			msgid = t.Id
+		}
+
+		fromMe := false
+		if strings.HasPrefix(msgid, "me:") {
+			fromMe = true
+			msgid = msgid[len("me:"):]
+		}
+		reaction := t.Body
+		if reaction == "remove" {
+			reaction = ""
+		}
+
+		msg := &waE2E.Message{
+			ReactionMessage: &waE2E.ReactionMessage{
+				Key: &waCommon.MessageKey{
+					RemoteJID: proto.String(recipient.String()),
+					FromMe:    proto.Bool(fromMe),
+					ID:        proto.String(msgid),
+				},
+				Text:              proto.String(reaction),
+				GroupingKey:       proto.String(reaction),
+				SenderTimestampMS: proto.Int64(time.Now().UnixMilli()),
+			},
+		}
+
+		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// How long a pinned message stays pinned, in seconds
+var pinDurations = map[string]uint32{
+	"24h": 24 * 60 * 60,
+	"1d":  24 * 60 * 60,
+	"7d":  7 * 24 * 60 * 60,
+	"30d": 30 * 24 * 60 * 60,
+}
+
+// Pins a message in a chat for everyone
+func (s *server) PinMessage() http.HandlerFunc {
+	return s.updateMessageAddOn("pin")
+}
+
+// Unpins a message
+func (s *server) UnpinMessage() http.HandlerFunc {
+	return s.updateMessageAddOn("unpin")
+}
+
+// Keeps a message in a disappearing messages chat
+func (s *server) KeepMessage() http.HandlerFunc {
+	return s.updateMessageAddOn("keep")
+}
+
+// Lets a kept message disappear again
+func (s *server) UnkeepMessage() http.HandlerFunc {
+	return s.updateMessageAddOn("unkeep")
+}
+
+// Sends a pin or keep-in-chat message targeting an existing message. As in reactions,
+// the Id of our own messages must be prefixed with "me:"
+func (s *server) updateMessageAddOn(action string) http.HandlerFunc {
+
+	type messageAddOnStruct struct {
+		Phone       string
+		Id          string
+		Participant string
+		Duration    string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t messageAddOnStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		if t.Id == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Id in Payload"))
+			return
+		}
+
+		recipient, ok := parseJID(t.Phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			return
+		}
+
+		key, err := buildMessageKey(userid, recipient, t.Id, t.Participant)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+		now := time.Now().UnixMilli()
+
+		var msg *waE2E.Message
+		switch action {
+		case "pin":
+			duration := pinDurations["7d"]
+			if t.Duration != "" {
+				duration, ok = pinDurations[strings.ToLower(t.Duration)]
+				if !ok {
+					s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid Duration. Allowed values: '24h', '7d', '30d'"))
+					return
+				}
+			}
+			msg = &waE2E.Message{
+				PinInChatMessage: &waE2E.PinInChatMessage{
+					Key:               key,
+					Type:              waE2E.PinInChatMessage_PIN_FOR_ALL.Enum(),
+					SenderTimestampMS: proto.Int64(now),
+				},
+				MessageContextInfo: &waE2E.MessageContextInfo{
+					MessageAddOnDurationInSecs: proto.Uint32(duration),
+				},
+			}
+		case "unpin":
+			msg = &waE2E.Message{
+				PinInChatMessage: &waE2E.PinInChatMessage{
+					Key:               key,
+					Type:              waE2E.PinInChatMessage_UNPIN_FOR_ALL.Enum(),
+					SenderTimestampMS: proto.Int64(now),
+				},
+			}
+		case "keep", "unkeep":
+			keepType := waE2E.KeepType_KEEP_FOR_ALL
+			if action == "unkeep" {
+				keepType = waE2E.KeepType_UNDO_KEEP_FOR_ALL
+			}
+			msg = &waE2E.Message{
+				KeepInChatMessage: &waE2E.KeepInChatMessage{
+					Key:         key,
+					KeepType:    keepType.Enum(),
+					TimestampMS: proto.Int64(now),
+				},
+			}
+		}
+
+		client := clientManager.GetWhatsmeowClient(userid)
+		msgid := client.GenerateMessageID()
+
+		resp, err := client.SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Str("target", key.GetID()).Str("action", action).Msg("Message sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Forwards an existing message to one or more chats
+func (s *server) ForwardMessage() http.HandlerFunc {
+
+	type forwardStruct struct {
+		Id       string
+		Message  *waE2E.Message
+		Phones   []string
+		Reupload bool
+	}
+
+	type forwardResult struct {
+		Phone     string
+		Id        string     `json:",omitempty"`
+		Timestamp *time.Time `json:",omitempty"`
+		Error     string     `json:",omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t forwardStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if len(t.Phones) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phones in Payload"))
+			return
+		}
+
+		// The message is taken from the payload when present, otherwise from the recent messages cache
+		original := t.Message
+		if original == nil {
+			if t.Id == "" {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Id or Message in Payload"))
+				return
+			}
+			cached, found := recallMessage(userid, strings.TrimPrefix(t.Id, "me:"))
+			if !found {
+				s.Respond(w, r, http.StatusNotFound, errors.New("Message not found, send it in the Message field"))
+				return
+			}
+			original = cached.Message
+		}
+
+		targets, err := parseJIDList(t.Phones)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		forwarded, err := forwardMessage(original)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		client := clientManager.GetWhatsmeowClient(userid)
+
+		if t.Reupload {
+			if err = reuploadMedia(client, forwarded); err != nil {
+				s.Respond(w, r, http.StatusInternalServerError, err)
+				return
+			}
+		}
+
+		results := []forwardResult{}
+		for i, target := range targets {
+			result := forwardResult{Phone: t.Phones[i]}
+			msg := proto.Clone(forwarded).(*waE2E.Message)
+			applyExpiration(client, userid, target, msg)
+
+			msgid := client.GenerateMessageID()
+			resp, err := client.SendMessage(context.Background(), target, msg, whatsmeow.SendRequestExtra{ID: msgid})
+			if err != nil {
+				log.Error().Err(err).Str("target", target.String()).Msg("Failed to forward message")
+				result.Error = err.Error()
+			} else {
+				rememberMessage(userid, msgid, target, *client.Store.ID, msg)
+				result.Id = msgid
+				result.Timestamp = &resp.Timestamp
+			}
+			results = append(results, result)
+		}
+
+		log.Info().Int("targets", len(targets)).Msg("Message forwarded")
+		response := map[string]interface{}{"Details": "Sent", "Messages": results}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Archives or unarchives a chat
+func (s *server) ArchiveChat() http.HandlerFunc {
+	return s.manageChat("archive")
+}
+
+// Pins or unpins a chat in the chat list
+func (s *server) PinChat() http.HandlerFunc {
+	return s.manageChat("pin")
+}
+
+// Mutes or unmutes a chat
+func (s *server) MuteChat() http.HandlerFunc {
+	return s.manageChat("mute")
+}
+
+// Marks a chat as unread or read
+func (s *server) MarkChatUnread() http.HandlerFunc {
+	return s.manageChat("unread")
+}
+
+// Clears all messages of a chat
+func (s *server) ClearChat() http.HandlerFunc {
+	return s.manageChat("clear")
+}
+
+// Deletes a chat
+func (s *server) DeleteChat() http.HandlerFunc {
+	return s.manageChat("delete")
+}
+
+// Sends an app state patch changing a chat, so the change shows on every linked device
+func (s *server) manageChat(action string) http.HandlerFunc {
+
+	// The flag of the action is required, so a missing field never does the opposite
+	type manageChatStruct struct {
+		Phone   string
+		Archive *bool
+		Pin     *bool
+		Mute    *bool
+		Until   string
+		Unread  *bool
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t manageChatStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		chat, ok := parseJID(t.Phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			return
+		}
+
+		missing := ""
+		switch {
+		case action == "archive" && t.Archive == nil:
+			missing = "Archive"
+		case action == "pin" && t.Pin == nil:
+			missing = "Pin"
+		case action == "mute" && t.Mute == nil:
+			missing = "Mute"
+		case action == "unread" && t.Unread == nil:
+			missing = "Unread"
+		}
+		if missing != "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("Missing %s in Payload", missing)))
+			return
+		}
+
+		var patch appstate.PatchInfo
+		var details string
+		switch action {
+		case "archive":
+			// The last message is optional when archiving, whatsmeow falls back to now
+			timestamp, key, _ := chatLastMessage(clientManager.GetWhatsmeowClient(userid), userid, chat)
+			patch = appstate.BuildArchive(chat, *t.Archive, timestamp, key)
+			details = "Chat unarchived"
+			if *t.Archive {
+				details = "Chat archived"
+			}
+		case "pin":
+			patch = appstate.BuildPin(chat, *t.Pin)
+			details = "Chat unpinned"
+			if *t.Pin {
+				details = "Chat pinned"
+			}
+		case "mute":
+			// Without Until the chat is muted forever
+			var duration time.Duration
+			if *t.Mute && t.Until != "" {
+				until, err := time.Parse(time.RFC3339, t.Until)
+				if err != nil {
+					s.Respond(w, r, http.StatusBadRequest, errors.New("Until must be a RFC3339 timestamp"))
+					return
+				}
+				duration = time.Until(until)
+				if duration <= 0 {
+					s.Respond(w, r, http.StatusBadRequest, errors.New("Until must be in the future"))
+					return
+				}
+			}
+			patch = appstate.BuildMute(chat, *t.Mute, duration)
+			details = "Chat unmuted"
+			if *t.Mute {
+				details = "Chat muted"
+			}
+		case "unread":
+			patch, err = buildMarkChatAsRead(clientManager.GetWhatsmeowClient(userid), userid, chat, !*t.Unread)
+			details = "Chat marked as read"
+			if *t.Unread {
+				details = "Chat marked as unread"
+			}
+		case "clear":
+			patch, err = buildClearChat(clientManager.GetWhatsmeowClient(userid), userid, chat)
+			details = "Chat cleared"
+		case "delete":
+			patch, err = buildDeleteChat(clientManager.GetWhatsmeowClient(userid), userid, chat)
+			details = "Chat deleted"
+		}
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SendAppState(patch)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Str("action", action).Msg("Failed to update chat")
+			msg := fmt.Sprintf("Failed to update chat: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": details}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Sets the disappearing messages timer of a chat or group
+func (s *server) SetChatEphemeral() http.HandlerFunc {
+
+	type setChatEphemeralStruct struct {
+		Phone    string
+		Duration string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setChatEphemeralStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Phone == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+			return
+		}
+
+		chat, ok := parseJID(t.Phone)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			return
+		}
+
+		timer, ok := parseDisappearingTimer(t.Duration)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid Duration. Allowed values: '24h', '7d', '90d', 'off'"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SetDisappearingTimer(chat, timer)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set chat disappearing timer")
+			msg := fmt.Sprintf("Failed to set chat disappearing timer: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+		ephemeralStore.Set(userid, chat, uint32(timer.Seconds()))
+
+		response := map[string]interface{}{"Details": "Chat disappearing timer set successfully"}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Mark messages as read
+func (s *server) MarkRead() http.HandlerFunc {
+
+	type markReadStruct struct {
+		Id     []string
+		Chat   types.JID
+		Sender types.JID
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t markReadStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Chat.String() == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Chat in Payload"))
+			return
+		}
+
+		if len(t.Id) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Id in Payload"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).MarkRead(t.Id, time.Now(), t.Chat, t.Sender)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("Failure marking messages as read"))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Message(s) marked as read"}
+		responseJson, err := json.Marshal(response)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+		return
+	}
+}
+
+// Community relationship of a group
+type groupCommunity struct {
+	IsParent          bool
+	LinkedParentJID   string
+	IsDefaultSubGroup bool
+}
+
+// Group information as returned by the group endpoints, with the community
+// relationship grouped under Community
+type groupInfoResponse struct {
+	types.GroupInfo
+	Community groupCommunity
+}
+
+func newGroupInfoResponse(info *types.GroupInfo) groupInfoResponse {
+	response := groupInfoResponse{
+		GroupInfo: *info,
+		Community: groupCommunity{
+			IsParent:          info.IsParent,
+			IsDefaultSubGroup: info.IsDefaultSubGroup,
+		},
+	}
+	if !info.LinkedParentJID.IsEmpty() {
+		response.Community.LinkedParentJID = info.LinkedParentJID.String()
+	}
+	return response
+}
+
+// List groups
+func (s *server) ListGroups() http.HandlerFunc {
+
+	type GroupCollection struct {
+		Groups []groupInfoResponse
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetJoinedGroups()
+
+		if err != nil {
+			msg := fmt.Sprintf("Failed to get group list: %v", err)
+			log.Error().Msg(msg)
+			s.Respond(w, r, http.StatusInternalServerError, msg)
+			return
+		}
+
+		gc := new(GroupCollection)
+		for _, info := range resp {
+			gc.Groups = append(gc.Groups, newGroupInfoResponse(info))
+		}
+
+		responseJson, err := json.Marshal(gc)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Get group info
+func (s *server) GetGroupInfo() http.HandlerFunc {
+
+	type getGroupInfoStruct struct {
+		GroupJID string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		// Get GroupJID from query parameter
+		groupJID := r.URL.Query().Get("groupJID")
+		if groupJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing groupJID parameter"))
+			return
+		}
+
+		group, ok := parseJID(groupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetGroupInfo(group)
+
+		if err != nil {
+			msg := fmt.Sprintf("Failed to get group info: %v", err)
+			log.Error().Msg(msg)
+			s.Respond(w, r, http.StatusInternalServerError, msg)
+			return
+		}
+
+		responseJson, err := json.Marshal(newGroupInfoResponse(resp))
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Get group invite link
+func (s *server) GetGroupInviteLink() http.HandlerFunc {
+
+	type getGroupInfoStruct struct {
+		GroupJID string
+		Reset    bool
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		// Get GroupJID from query parameter
+		groupJID := r.URL.Query().Get("groupJID")
+		if groupJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing groupJID parameter"))
+			return
+		}
+
+		// Get reset parameter
+		resetParam := r.URL.Query().Get("reset")
+		reset := false
+		if resetParam != "" {
+			var err error
+			reset, err = strconv.ParseBool(resetParam)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid reset parameter, must be true or false"))
+				return
+			}
+		}
+
+		group, ok := parseJID(groupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetGroupInviteLink(group, reset)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get group invite link")
+			msg := fmt.Sprintf("Failed to get group invite link: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, msg)
+			return
+		}
+
+		response := map[string]interface{}{"InviteLink": resp}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Set group photo
+func (s *server) SetGroupPhoto() http.HandlerFunc {
+
+	type setGroupPhotoStruct struct {
+		GroupJID string
+		Image    string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setGroupPhotoStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		if t.Image == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Image in Payload"))
+			return
+		}
+
+		var filedata []byte
+
+		if t.Image[0:13] == "data:image/jp" {
+			var dataURL, err = dataurl.DecodeString(t.Image)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
+				return
+			} else {
+				filedata = dataURL.Data
+			}
+		} else {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Image data should start with \"data:image/jpeg;base64,\""))
+			return
+		}
+
+		picture_id, err := clientManager.GetWhatsmeowClient(userid).SetGroupPhoto(group, filedata)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group photo")
+			msg := fmt.Sprintf("Failed to set group photo: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, msg)
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Group Photo set successfully", "PictureID": picture_id}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Set group name
+func (s *server) SetGroupName() http.HandlerFunc {
+
+	type setGroupNameStruct struct {
+		GroupJID string
+		Name     string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setGroupNameStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		if t.Name == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name in Payload"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SetGroupName(group, t.Name)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group name")
+			msg := fmt.Sprintf("Failed to set group name: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, msg)
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Group Name set successfully"}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Create group
+func (s *server) CreateGroup() http.HandlerFunc {
+
+	type createGroupStruct struct {
+		Name         string
+		Participants []string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t createGroupStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Name == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name in Payload"))
+			return
+		}
+
+		if len(t.Participants) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Participants in Payload"))
+			return
+		}
+
+		participants, err := parseJIDList(t.Participants)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		resp, err := clientManager.GetWhatsmeowClient(userid).CreateGroup(whatsmeow.ReqCreateGroup{
+			Name:         t.Name,
+			Participants: participants,
+		})
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to create group")
+			msg := fmt.Sprintf("Failed to create group: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		responseJson, err := json.Marshal(newGroupInfoResponse(resp))
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Leave group
+func (s *server) LeaveGroup() http.HandlerFunc {
+
+	type leaveGroupStruct struct {
+		GroupJID string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t leaveGroupStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.GroupJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing GroupJID in Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).LeaveGroup(group)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to leave group")
+			msg := fmt.Sprintf("Failed to leave group: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Left group successfully"}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Join group using an invite link
+func (s *server) JoinGroup() http.HandlerFunc {
+
+	type joinGroupStruct struct {
+		Code string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t joinGroupStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Code == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Code in Payload"))
+			return
+		}
+
+		group, err := clientManager.GetWhatsmeowClient(userid).JoinGroupWithLink(t.Code)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to join group")
+			msg := fmt.Sprintf("Failed to join group: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Group joined successfully", "GroupJID": group.String()}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Get group info from an invite link without joining
+func (s *server) GetGroupInviteInfo() http.HandlerFunc {
+
+	type groupInviteInfoStruct struct {
+		Code string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t groupInviteInfoStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		if t.Code == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Code in Payload"))
+			return
+		}
+
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetGroupInfoFromLink(t.Code)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get group invite info")
+			msg := fmt.Sprintf("Failed to get group invite info: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		responseJson, err := json.Marshal(newGroupInfoResponse(resp))
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Add, remove, promote or demote group participants
+func (s *server) UpdateGroupParticipants() http.HandlerFunc {
+
+	type updateGroupParticipantsStruct struct {
+		GroupJID     string
+		Participants []string
+		Action       string
+	}
+
+	type participantResult struct {
+		JID     string
+		Success bool
+		Error   int
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t updateGroupParticipantsStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		if len(t.Participants) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Participants in Payload"))
+			return
+		}
+
+		var action whatsmeow.ParticipantChange
+		switch t.Action {
+		case "add":
+			action = whatsmeow.ParticipantChangeAdd
+		case "remove":
+			action = whatsmeow.ParticipantChangeRemove
+		case "promote":
+			action = whatsmeow.ParticipantChangePromote
+		case "demote":
+			action = whatsmeow.ParticipantChangeDemote
+		default:
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid Action. Allowed values: 'add', 'remove', 'promote', 'demote'"))
+			return
+		}
+
+		participants, err := parseJIDList(t.Participants)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		resp, err := clientManager.GetWhatsmeowClient(userid).UpdateGroupParticipants(group, participants, action)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to update group participants")
+			msg := fmt.Sprintf("Failed to update group participants: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		results := []participantResult{}
+		for _, item := range resp {
+			results = append(results, participantResult{JID: item.JID.String(), Success: item.Error == 0, Error: item.Error})
+		}
+
+		response := map[string]interface{}{"Details": "Group participants updated", "Participants": results}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Sets whether only admins can send messages to the group
+func (s *server) SetGroupAnnounce() http.HandlerFunc {
+
+	type setGroupAnnounceStruct struct {
+		GroupJID string
+		Announce bool
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setGroupAnnounceStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SetGroupAnnounce(group, t.Announce)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group announce mode")
+			msg := fmt.Sprintf("Failed to set group announce mode: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Group announce mode set successfully"}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Sets whether only admins can edit group info
+func (s *server) SetGroupLocked() http.HandlerFunc {
+
+	type setGroupLockedStruct struct {
+		GroupJID string
+		Locked   bool
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setGroupLockedStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SetGroupLocked(group, t.Locked)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group locked mode")
+			msg := fmt.Sprintf("Failed to set group locked mode: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Group locked mode set successfully"}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Sets whether new members need admin approval to join the group
+func (s *server) SetGroupJoinApproval() http.HandlerFunc {
+
+	type setGroupJoinApprovalStruct struct {
+		GroupJID     string
+		JoinApproval bool
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setGroupJoinApprovalStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		err = clientManager.GetWhatsmeowClient(userid).SetGroupJoinApprovalMode(group, t.JoinApproval)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group join approval mode")
+			msg := fmt.Sprintf("Failed to set group join approval mode: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Group join approval mode set successfully"}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Sets or clears group topic (description)
+func (s *server) SetGroupTopic() http.HandlerFunc {
+
+	type setGroupTopicStruct struct {
+		GroupJID string
+		Topic    string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t setGroupTopicStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		// An empty topic removes the current one
+		err = clientManager.GetWhatsmeowClient(userid).SetGroupTopic(group, "", "", t.Topic)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group topic")
+			msg := fmt.Sprintf("Failed to set group topic: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		details := "Group topic set successfully"
+		if t.Topic == "" {
+			details = "Group topic removed successfully"
+		}
+		response := map[string]interface{}{"Details": details}
+		responseJson, err := json.Marshal(response)
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
@@ -2306,13 +6038,12 @@ func (s *server) GetContacts() http.HandlerFunc {
 	}
 }
 
-// Sets Chat Presence (typing/paused/recording audio)
-func (s *server) ChatPresence() http.HandlerFunc {
+// Sets the disappearing messages timer for a group
+func (s *server) SetGroupEphemeral() http.HandlerFunc {
 
-	type chatPresenceStruct struct {
-		Phone string
-		State string
-		Media types.ChatPresenceMedia
+	type setGroupEphemeralStruct struct {
+		GroupJID string
+		Duration string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2326,57 +6057,127 @@ func (s *server) ChatPresence() http.HandlerFunc {
 		}
 
 		decoder := json.NewDecoder(r.Body)
-		var t chatPresenceStruct
+		var t setGroupEphemeralStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		if len(t.Phone) < 1 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
 			return
 		}
 
-		if len(t.State) < 1 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing State in Payload"))
+		timer, ok := parseDisappearingTimer(t.Duration)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid Duration. Allowed values: '24h', '7d', '90d', 'off'"))
 			return
 		}
 
-		jid, ok := parseJID(t.Phone)
+		err = clientManager.GetWhatsmeowClient(userid).SetDisappearingTimer(group, timer)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group disappearing timer")
+			msg := fmt.Sprintf("Failed to set group disappearing timer: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+		ephemeralStore.Set(userid, group, uint32(timer.Seconds()))
+
+		response := map[string]interface{}{"Details": "Group disappearing timer set successfully"}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Lists pending requests to join a group
+func (s *server) GetGroupJoinRequests() http.HandlerFunc {
+
+	type joinRequest struct {
+		JID         string
+		RequestedAt time.Time
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		// Get GroupJID from query parameter
+		groupJID := r.URL.Query().Get("groupJID")
+		if groupJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing groupJID parameter"))
+			return
+		}
+
+		group, ok := parseJID(groupJID)
 		if !ok {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Phone"))
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
 			return
 		}
 
-		err = clientManager.GetWhatsmeowClient(userid).SendChatPresence(jid, types.ChatPresence(t.State), types.ChatPresenceMedia(t.Media))
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetGroupRequestParticipants(group)
+
 		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New("Failure sending chat presence to Whatsapp servers"))
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get group join requests")
+			msg := fmt.Sprintf("Failed to get group join requests: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
 			return
 		}
 
-		response := map[string]interface{}{"Details": "Chat presence set successfuly"}
+		requests := []joinRequest{}
+		for _, item := range resp {
+			requests = append(requests, joinRequest{JID: item.JID.String(), RequestedAt: item.RequestedAt})
+		}
+
+		response := map[string]interface{}{"GroupJID": group.String(), "Requests": requests}
 		responseJson, err := json.Marshal(response)
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
+
 		return
 	}
 }
 
-// Downloads Image and returns base64 representation
-func (s *server) DownloadImage() http.HandlerFunc {
+// Approves pending requests to join a group
+func (s *server) ApproveGroupJoinRequests() http.HandlerFunc {
+	return s.updateGroupJoinRequests(whatsmeow.ParticipantChangeApprove)
+}
 
-	type downloadImageStruct struct {
-		Url           string
-		DirectPath    string
-		MediaKey      []byte
-		Mimetype      string
-		FileEncSHA256 []byte
-		FileSHA256    []byte
-		FileLength    uint64
+// Rejects pending requests to join a group
+func (s *server) RejectGroupJoinRequests() http.HandlerFunc {
+	return s.updateGroupJoinRequests(whatsmeow.ParticipantChangeReject)
+}
+
+func (s *server) updateGroupJoinRequests(action whatsmeow.ParticipantRequestChange) http.HandlerFunc {
+
+	type updateGroupJoinRequestsStruct struct {
+		GroupJID     string
+		Participants []string
+	}
+
+	type participantResult struct {
+		JID     string
+		Success bool
+		Error   int
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2384,79 +6185,208 @@ func (s *server) DownloadImage() http.HandlerFunc {
 		txtid := r.Context().Value("userinfo").(Values).Get("Id")
 		userid, _ := strconv.Atoi(txtid)
 
-		mimetype := ""
-		var imgdata []byte
-
 		if clientManager.GetWhatsmeowClient(userid) == nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
 			return
 		}
 
-		// check/creates user directory for files
-		userDirectory := filepath.Join(s.exPath, "files", "user_"+txtid)
-		_, err := os.Stat(userDirectory)
-		if os.IsNotExist(err) {
-			errDir := os.MkdirAll(userDirectory, 0751)
-			if errDir != nil {
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create user directory (%s)", userDirectory)))
-				return
-			}
+		decoder := json.NewDecoder(r.Body)
+		var t updateGroupJoinRequestsStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		if len(t.Participants) < 1 {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Participants in Payload"))
+			return
+		}
+
+		participants, err := parseJIDList(t.Participants)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		resp, err := clientManager.GetWhatsmeowClient(userid).UpdateGroupRequestParticipants(group, participants, action)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Str("action", string(action)).Msg("Failed to update group join requests")
+			msg := fmt.Sprintf("Failed to %s group join requests: %v", action, err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		results := []participantResult{}
+		for _, item := range resp {
+			results = append(results, participantResult{JID: item.JID.String(), Success: item.Error == 0, Error: item.Error})
+		}
+
+		response := map[string]interface{}{"Details": "Group join requests updated", "Participants": results}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Create community
+func (s *server) CreateCommunity() http.HandlerFunc {
+
+	type createCommunityStruct struct {
+		Name        string
+		Description string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
 		}
 
 		decoder := json.NewDecoder(r.Body)
-		var t downloadImageStruct
-		err = decoder.Decode(&t)
+		var t createCommunityStruct
+		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		msg := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
-			URL:           proto.String(t.Url),
-			DirectPath:    proto.String(t.DirectPath),
-			MediaKey:      t.MediaKey,
-			Mimetype:      proto.String(t.Mimetype),
-			FileEncSHA256: t.FileEncSHA256,
-			FileSHA256:    t.FileSHA256,
-			FileLength:    &t.FileLength,
-		}}
+		if t.Name == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name in Payload"))
+			return
+		}
 
-		img := msg.GetImageMessage()
+		client := clientManager.GetWhatsmeowClient(userid)
+		resp, err := client.CreateGroup(whatsmeow.ReqCreateGroup{
+			Name:        t.Name,
+			GroupParent: types.GroupParent{IsParent: true},
+		})
 
-		if img != nil {
-			imgdata, err = clientManager.GetWhatsmeowClient(userid).Download(img)
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to create community")
+			msg := fmt.Sprintf("Failed to create community: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		if t.Description != "" {
+			err = client.SetGroupTopic(resp.JID, "", "", t.Description)
 			if err != nil {
-				log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to download image")
-				msg := fmt.Sprintf("Failed to download image %v", err)
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
-				return
+				log.Warn().Str("error", fmt.Sprintf("%v", err)).Str("community", resp.JID.String()).Msg("Failed to set community description")
 			}
-			mimetype = img.GetMimetype()
 		}
 
-		dataURL := dataurl.New(imgdata, mimetype)
-		response := map[string]interface{}{"Mimetype": mimetype, "Data": dataURL.String()}
-		responseJson, err := json.Marshal(response)
+		responseJson, err := json.Marshal(newGroupInfoResponse(resp))
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
+
 		return
 	}
 }
 
-// Downloads Document and returns base64 representation
-func (s *server) DownloadDocument() http.HandlerFunc {
+// Links an existing group to a community
+func (s *server) LinkCommunityGroup() http.HandlerFunc {
+	return s.updateCommunityLink(true)
+}
 
-	type downloadDocumentStruct struct {
-		Url           string
-		DirectPath    string
-		MediaKey      []byte
-		Mimetype      string
-		FileEncSHA256 []byte
-		FileSHA256    []byte
-		FileLength    uint64
+// Unlinks a group from a community
+func (s *server) UnlinkCommunityGroup() http.HandlerFunc {
+	return s.updateCommunityLink(false)
+}
+
+func (s *server) updateCommunityLink(link bool) http.HandlerFunc {
+
+	type communityLinkStruct struct {
+		CommunityJID string
+		GroupJID     string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		txtid := r.Context().Value("userinfo").(Values).Get("Id")
+		userid, _ := strconv.Atoi(txtid)
+
+		if clientManager.GetWhatsmeowClient(userid) == nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var t communityLinkStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			return
+		}
+
+		community, ok := parseJID(t.CommunityJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Community JID"))
+			return
+		}
+
+		group, ok := parseJID(t.GroupJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			return
+		}
+
+		details := "Group linked successfully"
+		if link {
+			err = clientManager.GetWhatsmeowClient(userid).LinkGroup(community, group)
+		} else {
+			details = "Group unlinked successfully"
+			err = clientManager.GetWhatsmeowClient(userid).UnlinkGroup(community, group)
+		}
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Bool("link", link).Msg("Failed to update community link")
+			msg := fmt.Sprintf("Failed to update community link: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": details}
+		responseJson, err := json.Marshal(response)
+
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, err)
+		} else {
+			s.Respond(w, r, http.StatusOK, string(responseJson))
+		}
+
+		return
+	}
+}
+
+// Lists the groups linked to a community
+func (s *server) ListCommunitySubGroups() http.HandlerFunc {
+
+	type subGroup struct {
+		JID               string
+		Name              string
+		IsDefaultSubGroup bool
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2464,79 +6394,58 @@ func (s *server) DownloadDocument() http.HandlerFunc {
 		txtid := r.Context().Value("userinfo").(Values).Get("Id")
 		userid, _ := strconv.Atoi(txtid)
 
-		mimetype := ""
-		var docdata []byte
-
 		if clientManager.GetWhatsmeowClient(userid) == nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
 			return
 		}
 
-		// check/creates user directory for files
-		userDirectory := filepath.Join(s.exPath, "files", "user_"+txtid)
-		_, err := os.Stat(userDirectory)
-		if os.IsNotExist(err) {
-			errDir := os.MkdirAll(userDirectory, 0751)
-			if errDir != nil {
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create user directory (%s)", userDirectory)))
-				return
-			}
+		// Get CommunityJID from query parameter
+		communityJID := r.URL.Query().Get("communityJID")
+		if communityJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing communityJID parameter"))
+			return
 		}
 
-		decoder := json.NewDecoder(r.Body)
-		var t downloadDocumentStruct
-		err = decoder.Decode(&t)
-		if err != nil {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+		community, ok := parseJID(communityJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Community JID"))
 			return
 		}
 
-		msg := &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
-			URL:           proto.String(t.Url),
-			DirectPath:    proto.String(t.DirectPath),
-			MediaKey:      t.MediaKey,
-			Mimetype:      proto.String(t.Mimetype),
-			FileEncSHA256: t.FileEncSHA256,
-			FileSHA256:    t.FileSHA256,
-			FileLength:    &t.FileLength,
-		}}
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetSubGroups(community)
 
-		doc := msg.GetDocumentMessage()
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get community subgroups")
+			msg := fmt.Sprintf("Failed to get community subgroups: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
 
-		if doc != nil {
-			docdata, err = clientManager.GetWhatsmeowClient(userid).Download(doc)
-			if err != nil {
-				log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to download document")
-				msg := fmt.Sprintf("Failed to download document %v", err)
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
-				return
-			}
-			mimetype = doc.GetMimetype()
+		groups := []subGroup{}
+		for _, item := range resp {
+			groups = append(groups, subGroup{JID: item.JID.String(), Name: item.Name, IsDefaultSubGroup: item.IsDefaultSubGroup})
 		}
 
-		dataURL := dataurl.New(docdata, mimetype)
-		response := map[string]interface{}{"Mimetype": mimetype, "Data": dataURL.String()}
+		response := map[string]interface{}{"CommunityJID": community.String(), "Groups": groups}
 		responseJson, err := json.Marshal(response)
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
+
 		return
 	}
 }
 
-// Downloads Video and returns base64 representation
-func (s *server) DownloadVideo() http.HandlerFunc {
+// Sends a text message to the announcement group of a community
+func (s *server) SendCommunityAnnouncement() http.HandlerFunc {
 
-	type downloadVideoStruct struct {
-		Url           string
-		DirectPath    string
-		MediaKey      []byte
-		Mimetype      string
-		FileEncSHA256 []byte
-		FileSHA256    []byte
-		FileLength    uint64
+	type communityAnnouncementStruct struct {
+		CommunityJID string
+		Body         string
+		Id           string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2544,79 +6453,91 @@ func (s *server) DownloadVideo() http.HandlerFunc {
 		txtid := r.Context().Value("userinfo").(Values).Get("Id")
 		userid, _ := strconv.Atoi(txtid)
 
-		mimetype := ""
-		var docdata []byte
-
 		if clientManager.GetWhatsmeowClient(userid) == nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
 			return
 		}
 
-		// check/creates user directory for files
-		userDirectory := filepath.Join(s.exPath, "files", "user_"+txtid)
-		_, err := os.Stat(userDirectory)
-		if os.IsNotExist(err) {
-			errDir := os.MkdirAll(userDirectory, 0751)
-			if errDir != nil {
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create user directory (%s)", userDirectory)))
-				return
-			}
-		}
-
 		decoder := json.NewDecoder(r.Body)
-		var t downloadVideoStruct
-		err = decoder.Decode(&t)
+		var t communityAnnouncementStruct
+		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		msg := &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
-			URL:           proto.String(t.Url),
-			DirectPath:    proto.String(t.DirectPath),
-			MediaKey:      t.MediaKey,
-			Mimetype:      proto.String(t.Mimetype),
-			FileEncSHA256: t.FileEncSHA256,
-			FileSHA256:    t.FileSHA256,
-			FileLength:    &t.FileLength,
-		}}
+		if t.Body == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Body in Payload"))
+			return
+		}
 
-		doc := msg.GetVideoMessage()
+		community, ok := parseJID(t.CommunityJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Community JID"))
+			return
+		}
 
-		if doc != nil {
-			docdata, err = clientManager.GetWhatsmeowClient(userid).Download(doc)
-			if err != nil {
-				log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to download video")
-				msg := fmt.Sprintf("Failed to download video %v", err)
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
-				return
+		client := clientManager.GetWhatsmeowClient(userid)
+
+		subgroups, err := client.GetSubGroups(community)
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get community subgroups")
+			msg := fmt.Sprintf("Failed to get community subgroups: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		var announcement types.JID
+		for _, item := range subgroups {
+			if item.IsDefaultSubGroup {
+				announcement = item.JID
+				break
 			}
-			mimetype = doc.GetMimetype()
+		}
+		if announcement.IsEmpty() {
+			s.Respond(w, r, http.StatusNotFound, errors.New("Community has no announcement group"))
+			return
 		}
 
-		dataURL := dataurl.New(docdata, mimetype)
-		response := map[string]interface{}{"Mimetype": mimetype, "Data": dataURL.String()}
+		msgid := t.Id
+		if msgid == "" {
+			msgid = client.GenerateMessageID()
+		}
+
+		msg := &waE2E.Message{
+			ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text: &t.Body,
+			},
+		}
+
+		applyExpiration(client, userid, announcement, msg)
+
+		resp, err := client.SendMessage(context.Background(), announcement, msg, whatsmeow.SendRequestExtra{ID: msgid})
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+		rememberMessage(userid, msgid, announcement, *client.Store.ID, msg)
+
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Str("group", announcement.String()).Msg("Community announcement sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid, "GroupJID": announcement.String()}
 		responseJson, err := json.Marshal(response)
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
+
 		return
 	}
 }
 
-// Downloads Audio and returns base64 representation
-func (s *server) DownloadAudio() http.HandlerFunc {
+// List newsletters
+func (s *server) ListNewsletter() http.HandlerFunc {
 
-	type downloadAudioStruct struct {
-		Url           string
-		DirectPath    string
-		MediaKey      []byte
-		Mimetype      string
-		FileEncSHA256 []byte
-		FileSHA256    []byte
-		FileLength    uint64
+	type NewsletterCollection struct {
+		Newsletter []types.NewsletterMetadata
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2624,75 +6545,60 @@ func (s *server) DownloadAudio() http.HandlerFunc {
 		txtid := r.Context().Value("userinfo").(Values).Get("Id")
 		userid, _ := strconv.Atoi(txtid)
 
-		mimetype := ""
-		var docdata []byte
-
 		if clientManager.GetWhatsmeowClient(userid) == nil {
 			s.Respond(w, r, http.StatusInternalServerError, errors.New("No session"))
 			return
 		}
 
-		// check/creates user directory for files
-		userDirectory := filepath.Join(s.exPath, "files", "user_"+txtid)
-		_, err := os.Stat(userDirectory)
-		if os.IsNotExist(err) {
-			errDir := os.MkdirAll(userDirectory, 0751)
-			if errDir != nil {
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Could not create user directory (%s)", userDirectory)))
-				return
-			}
-		}
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetSubscribedNewsletters()
 
-		decoder := json.NewDecoder(r.Body)
-		var t downloadAudioStruct
-		err = decoder.Decode(&t)
 		if err != nil {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+			msg := fmt.Sprintf("Failed to get newsletter list: %v", err)
+			log.Error().Msg(msg)
+			s.Respond(w, r, http.StatusInternalServerError, msg)
 			return
 		}
 
-		msg := &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
-			URL:           proto.String(t.Url),
-			DirectPath:    proto.String(t.DirectPath),
-			MediaKey:      t.MediaKey,
-			Mimetype:      proto.String(t.Mimetype),
-			FileEncSHA256: t.FileEncSHA256,
-			FileSHA256:    t.FileSHA256,
-			FileLength:    &t.FileLength,
-		}}
-
-		doc := msg.GetAudioMessage()
-
-		if doc != nil {
-			docdata, err = clientManager.GetWhatsmeowClient(userid).Download(doc)
-			if err != nil {
-				log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to download audio")
-				msg := fmt.Sprintf("Failed to download audio %v", err)
-				s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
-				return
-			}
-			mimetype = doc.GetMimetype()
+		gc := new(NewsletterCollection)
+		gc.Newsletter = []types.NewsletterMetadata{}
+		for _, info := range resp {
+			gc.Newsletter = append(gc.Newsletter, *info)
 		}
 
-		dataURL := dataurl.New(docdata, mimetype)
-		response := map[string]interface{}{"Mimetype": mimetype, "Data": dataURL.String()}
-		responseJson, err := json.Marshal(response)
+		responseJson, err := json.Marshal(gc)
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
+
 		return
 	}
 }
 
-// React
-func (s *server) React() http.HandlerFunc {
+// Decodes a newsletter picture data URL into a square JPEG
+func newsletterPicture(data string) ([]byte, error) {
+	if !strings.HasPrefix(data, "data:image") {
+		return nil, errors.New("Picture data should start with \"data:image/jpeg;base64,\"")
+	}
+	dataURL, err := dataurl.DecodeString(data)
+	if err != nil {
+		return nil, errors.New("Could not decode base64 encoded data from payload")
+	}
+	picture, err := profilePicture(dataURL.Data)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not process picture: %v", err))
+	}
+	return picture, nil
+}
 
-	type textStruct struct {
-		Phone string
-		Body  string
-		Id    string
+// Creates a newsletter (channel)
+func (s *server) CreateNewsletter() http.HandlerFunc {
+
+	type createNewsletterStruct struct {
+		Name        string
+		Description string
+		Picture     string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2705,73 +6611,39 @@ func (s *server) React() http.HandlerFunc {
 			return
 		}
 
-		msgid := ""
-		var resp whatsmeow.SendResponse
-
 		decoder := json.NewDecoder(r.Body)
-		var t textStruct
+		var t createNewsletterStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		if t.Phone == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Phone in Payload"))
-			return
-		}
-
-		if t.Body == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Body in Payload"))
-			return
-		}
-
-		recipient, ok := parseJID(t.Phone)
-		if !ok {
-			log.Error().Msg(fmt.Sprintf("%s", err))
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
-			return
-		}
-
-		if t.Id == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Id in Payload"))
+		if t.Name == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name in Payload"))
 			return
-		} else {
-			msgid = t.Id
-		}
-
-		fromMe := false
-		if strings.HasPrefix(msgid, "me:") {
-			fromMe = true
-			msgid = msgid[len("me:"):]
 		}
-		reaction := t.Body
-		if reaction == "remove" {
-			reaction = ""
-		}
-
-		msg := &waE2E.Message{
-			ReactionMessage: &waE2E.ReactionMessage{
-				Key: &waCommon.MessageKey{
-					RemoteJID: proto.String(recipient.String()),
-					FromMe:    proto.Bool(fromMe),
-					ID:        proto.String(msgid),
-				},
-				Text:              proto.String(reaction),
-				GroupingKey:       proto.String(reaction),
-				SenderTimestampMS: proto.Int64(time.Now().UnixMilli()),
-			},
+
+		params := whatsmeow.CreateNewsletterParams{Name: t.Name, Description: t.Description}
+		if t.Picture != "" {
+			params.Picture, err = newsletterPicture(t.Picture)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, err)
+				return
+			}
 		}
 
-		resp, err = clientManager.GetWhatsmeowClient(userid).SendMessage(context.Background(), recipient, msg, whatsmeow.SendRequestExtra{ID: msgid})
+		resp, err := clientManager.GetWhatsmeowClient(userid).CreateNewsletter(params)
+
 		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to create newsletter")
+			msg := fmt.Sprintf("Failed to create newsletter: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
 			return
 		}
 
-		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Msg("Message sent")
-		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid}
-		responseJson, err := json.Marshal(response)
+		responseJson, err := json.Marshal(resp)
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
@@ -2782,14 +6654,8 @@ func (s *server) React() http.HandlerFunc {
 	}
 }
 
-// Mark messages as read
-func (s *server) MarkRead() http.HandlerFunc {
-
-	type markReadStruct struct {
-		Id     []string
-		Chat   types.JID
-		Sender types.JID
-	}
+// Gets newsletter information
+func (s *server) GetNewsletterInfo() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -2801,46 +6667,48 @@ func (s *server) MarkRead() http.HandlerFunc {
 			return
 		}
 
-		decoder := json.NewDecoder(r.Body)
-		var t markReadStruct
-		err := decoder.Decode(&t)
-		if err != nil {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+		// Get NewsletterJID from query parameter
+		newsletterJID := r.URL.Query().Get("newsletterJID")
+		if newsletterJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing newsletterJID parameter"))
 			return
 		}
 
-		if t.Chat.String() == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Chat in Payload"))
+		newsletter, ok := parseJID(newsletterJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Newsletter JID"))
 			return
 		}
 
-		if len(t.Id) < 1 {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Id in Payload"))
-			return
-		}
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetNewsletterInfo(newsletter)
 
-		err = clientManager.GetWhatsmeowClient(userid).MarkRead(t.Id, time.Now(), t.Chat, t.Sender)
 		if err != nil {
-			s.Respond(w, r, http.StatusInternalServerError, errors.New("Failure marking messages as read"))
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get newsletter info")
+			msg := fmt.Sprintf("Failed to get newsletter info: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
 			return
 		}
 
-		response := map[string]interface{}{"Details": "Message(s) marked as read"}
-		responseJson, err := json.Marshal(response)
+		responseJson, err := json.Marshal(resp)
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
 			s.Respond(w, r, http.StatusOK, string(responseJson))
 		}
+
 		return
 	}
 }
 
-// List groups
-func (s *server) ListGroups() http.HandlerFunc {
+// Updates name, description and/or picture of a newsletter
+func (s *server) UpdateNewsletter() http.HandlerFunc {
 
-	type GroupCollection struct {
-		Groups []types.GroupInfo
+	type updateNewsletterStruct struct {
+		NewsletterJID string
+		Name          *string
+		Description   *string
+		Picture       string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2853,21 +6721,56 @@ func (s *server) ListGroups() http.HandlerFunc {
 			return
 		}
 
-		resp, err := clientManager.GetWhatsmeowClient(userid).GetJoinedGroups()
-
+		decoder := json.NewDecoder(r.Body)
+		var t updateNewsletterStruct
+		err := decoder.Decode(&t)
 		if err != nil {
-			msg := fmt.Sprintf("Failed to get group list: %v", err)
-			log.Error().Msg(msg)
-			s.Respond(w, r, http.StatusInternalServerError, msg)
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		gc := new(GroupCollection)
-		for _, info := range resp {
-			gc.Groups = append(gc.Groups, *info)
+		if t.NewsletterJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing NewsletterJID in Payload"))
+			return
 		}
 
-		responseJson, err := json.Marshal(gc)
+		if t.Name == nil && t.Description == nil && t.Picture == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name, Description or Picture in Payload"))
+			return
+		}
+
+		if t.Name != nil && *t.Name == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Name cannot be empty"))
+			return
+		}
+
+		newsletter, ok := parseJID(t.NewsletterJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Newsletter JID"))
+			return
+		}
+
+		updates := newsletterUpdate{Name: t.Name, Description: t.Description}
+		if t.Picture != "" {
+			updates.Picture, err = newsletterPicture(t.Picture)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		resp, err := updateNewsletter(clientManager.GetWhatsmeowClient(userid), newsletter, updates)
+
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to update newsletter")
+			msg := fmt.Sprintf("Failed to update newsletter: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
+			return
+		}
+
+		response := map[string]interface{}{"Details": "Newsletter updated successfully", "Newsletter": resp}
+		responseJson, err := json.Marshal(response)
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
@@ -2878,11 +6781,20 @@ func (s *server) ListGroups() http.HandlerFunc {
 	}
 }
 
-// Get group info
-func (s *server) GetGroupInfo() http.HandlerFunc {
+// Follows a newsletter
+func (s *server) FollowNewsletter() http.HandlerFunc {
+	return s.setNewsletterFollow(true)
+}
 
-	type getGroupInfoStruct struct {
-		GroupJID string
+// Unfollows a newsletter
+func (s *server) UnfollowNewsletter() http.HandlerFunc {
+	return s.setNewsletterFollow(false)
+}
+
+func (s *server) setNewsletterFollow(follow bool) http.HandlerFunc {
+
+	type newsletterFollowStruct struct {
+		NewsletterJID string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2895,29 +6807,43 @@ func (s *server) GetGroupInfo() http.HandlerFunc {
 			return
 		}
 
-		// Get GroupJID from query parameter
-		groupJID := r.URL.Query().Get("groupJID")
-		if groupJID == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing groupJID parameter"))
+		decoder := json.NewDecoder(r.Body)
+		var t newsletterFollowStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		group, ok := parseJID(groupJID)
+		if t.NewsletterJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing NewsletterJID in Payload"))
+			return
+		}
+
+		newsletter, ok := parseJID(t.NewsletterJID)
 		if !ok {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Newsletter JID"))
 			return
 		}
 
-		resp, err := clientManager.GetWhatsmeowClient(userid).GetGroupInfo(group)
+		client := clientManager.GetWhatsmeowClient(userid)
+		action := "follow"
+		if follow {
+			err = client.FollowNewsletter(newsletter)
+		} else {
+			action = "unfollow"
+			err = client.UnfollowNewsletter(newsletter)
+		}
 
 		if err != nil {
-			msg := fmt.Sprintf("Failed to get group info: %v", err)
-			log.Error().Msg(msg)
-			s.Respond(w, r, http.StatusInternalServerError, msg)
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg(fmt.Sprintf("Failed to %s newsletter", action))
+			msg := fmt.Sprintf("Failed to %s newsletter: %v", action, err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
 			return
 		}
 
-		responseJson, err := json.Marshal(resp)
+		response := map[string]interface{}{"Details": fmt.Sprintf("Newsletter %sed successfully", action)}
+		responseJson, err := json.Marshal(response)
 
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
@@ -2929,12 +6855,12 @@ func (s *server) GetGroupInfo() http.HandlerFunc {
 	}
 }
 
-// Get group invite link
-func (s *server) GetGroupInviteLink() http.HandlerFunc {
+// Mutes or unmutes a newsletter
+func (s *server) MuteNewsletter() http.HandlerFunc {
 
-	type getGroupInfoStruct struct {
-		GroupJID string
-		Reset    bool
+	type muteNewsletterStruct struct {
+		NewsletterJID string
+		Mute          bool
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2947,41 +6873,35 @@ func (s *server) GetGroupInviteLink() http.HandlerFunc {
 			return
 		}
 
-		// Get GroupJID from query parameter
-		groupJID := r.URL.Query().Get("groupJID")
-		if groupJID == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing groupJID parameter"))
+		decoder := json.NewDecoder(r.Body)
+		var t muteNewsletterStruct
+		err := decoder.Decode(&t)
+		if err != nil {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		// Get reset parameter
-		resetParam := r.URL.Query().Get("reset")
-		reset := false
-		if resetParam != "" {
-			var err error
-			reset, err = strconv.ParseBool(resetParam)
-			if err != nil {
-				s.Respond(w, r, http.StatusBadRequest, errors.New("Invalid reset parameter, must be true or false"))
-				return
-			}
+		if t.NewsletterJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing NewsletterJID in Payload"))
+			return
 		}
 
-		group, ok := parseJID(groupJID)
+		newsletter, ok := parseJID(t.NewsletterJID)
 		if !ok {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Newsletter JID"))
 			return
 		}
 
-		resp, err := clientManager.GetWhatsmeowClient(userid).GetGroupInviteLink(group, reset)
+		err = clientManager.GetWhatsmeowClient(userid).NewsletterToggleMute(newsletter, t.Mute)
 
 		if err != nil {
-			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get group invite link")
-			msg := fmt.Sprintf("Failed to get group invite link: %v", err)
-			s.Respond(w, r, http.StatusInternalServerError, msg)
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set newsletter mute")
+			msg := fmt.Sprintf("Failed to set newsletter mute: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
 			return
 		}
 
-		response := map[string]interface{}{"InviteLink": resp}
+		response := map[string]interface{}{"Details": "Newsletter mute set successfully"}
 		responseJson, err := json.Marshal(response)
 
 		if err != nil {
@@ -2994,13 +6914,8 @@ func (s *server) GetGroupInviteLink() http.HandlerFunc {
 	}
 }
 
-// Set group photo
-func (s *server) SetGroupPhoto() http.HandlerFunc {
-
-	type setGroupPhotoStruct struct {
-		GroupJID string
-		Image    string
-	}
+// Gets recent newsletter messages with their view and reaction counts
+func (s *server) GetNewsletterMessages() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -3012,50 +6927,53 @@ func (s *server) SetGroupPhoto() http.HandlerFunc {
 			return
 		}
 
-		decoder := json.NewDecoder(r.Body)
-		var t setGroupPhotoStruct
-		err := decoder.Decode(&t)
-		if err != nil {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
+		query := r.URL.Query()
+
+		// Get NewsletterJID from query parameter
+		newsletterJID := query.Get("newsletterJID")
+		if newsletterJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing newsletterJID parameter"))
 			return
 		}
 
-		group, ok := parseJID(t.GroupJID)
+		newsletter, ok := parseJID(newsletterJID)
 		if !ok {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Newsletter JID"))
 			return
 		}
 
-		if t.Image == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Image in Payload"))
-			return
+		params := &whatsmeow.GetNewsletterMessagesParams{Count: defaultNewsletterMessages}
+		if count := query.Get("count"); count != "" {
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 1 || n > maxNewsletterMessages {
+				s.Respond(w, r, http.StatusBadRequest, errors.New(fmt.Sprintf("count must be between 1 and %d", maxNewsletterMessages)))
+				return
+			}
+			params.Count = n
 		}
-
-		var filedata []byte
-
-		if t.Image[0:13] == "data:image/jp" {
-			var dataURL, err = dataurl.DecodeString(t.Image)
-			if err != nil {
-				s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
+		if before := query.Get("before"); before != "" {
+			n, err := strconv.Atoi(before)
+			if err != nil || n < 1 {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("before must be a message server id"))
 				return
-			} else {
-				filedata = dataURL.Data
 			}
-		} else {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Image data should start with \"data:image/jpeg;base64,\""))
-			return
+			params.Before = types.MessageServerID(n)
 		}
 
-		picture_id, err := clientManager.GetWhatsmeowClient(userid).SetGroupPhoto(group, filedata)
+		resp, err := clientManager.GetWhatsmeowClient(userid).GetNewsletterMessages(newsletter, params)
 
 		if err != nil {
-			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group photo")
-			msg := fmt.Sprintf("Failed to set group photo: %v", err)
-			s.Respond(w, r, http.StatusInternalServerError, msg)
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to get newsletter messages")
+			msg := fmt.Sprintf("Failed to get newsletter messages: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
 			return
 		}
 
-		response := map[string]interface{}{"Details": "Group Photo set successfully", "PictureID": picture_id}
+		if resp == nil {
+			resp = []*types.NewsletterMessage{}
+		}
+
+		response := map[string]interface{}{"NewsletterJID": newsletter.String(), "Messages": resp}
 		responseJson, err := json.Marshal(response)
 
 		if err != nil {
@@ -3068,12 +6986,11 @@ func (s *server) SetGroupPhoto() http.HandlerFunc {
 	}
 }
 
-// Set group name
-func (s *server) SetGroupName() http.HandlerFunc {
+// Subscribes to live view and reaction count updates of a newsletter
+func (s *server) SubscribeNewsletterUpdates() http.HandlerFunc {
 
-	type setGroupNameStruct struct {
-		GroupJID string
-		Name     string
+	type subscribeNewsletterStruct struct {
+		NewsletterJID string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -3087,34 +7004,34 @@ func (s *server) SetGroupName() http.HandlerFunc {
 		}
 
 		decoder := json.NewDecoder(r.Body)
-		var t setGroupNameStruct
+		var t subscribeNewsletterStruct
 		err := decoder.Decode(&t)
 		if err != nil {
 			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		group, ok := parseJID(t.GroupJID)
-		if !ok {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Group JID"))
+		if t.NewsletterJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing NewsletterJID in Payload"))
 			return
 		}
 
-		if t.Name == "" {
-			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Name in Payload"))
+		newsletter, ok := parseJID(t.NewsletterJID)
+		if !ok {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Newsletter JID"))
 			return
 		}
 
-		err = clientManager.GetWhatsmeowClient(userid).SetGroupName(group, t.Name)
+		duration, err := clientManager.GetWhatsmeowClient(userid).NewsletterSubscribeLiveUpdates(context.Background(), newsletter)
 
 		if err != nil {
-			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to set group name")
-			msg := fmt.Sprintf("Failed to set group name: %v", err)
-			s.Respond(w, r, http.StatusInternalServerError, msg)
+			log.Error().Str("error", fmt.Sprintf("%v", err)).Msg("Failed to subscribe to newsletter updates")
+			msg := fmt.Sprintf("Failed to subscribe to newsletter updates: %v", err)
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(msg))
 			return
 		}
 
-		response := map[string]interface{}{"Details": "Group Name set successfully"}
+		response := map[string]interface{}{"Details": "Subscribed to newsletter updates", "Duration": int(duration.Seconds())}
 		responseJson, err := json.Marshal(response)
 
 		if err != nil {
@@ -3127,11 +7044,16 @@ func (s *server) SetGroupName() http.HandlerFunc {
 	}
 }
 
-// List newsletters
-func (s *server) ListNewsletter() http.HandlerFunc {
+// Publishes a text, image or video post to a newsletter the user administers
+func (s *server) SendNewsletterMessage() http.HandlerFunc {
 
-	type NewsletterCollection struct {
-		Newsletter []types.NewsletterMetadata
+	type newsletterMessageStruct struct {
+		NewsletterJID string
+		Body          string
+		Image         string
+		Video         string
+		Caption       string
+		Id            string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -3144,22 +7066,82 @@ func (s *server) ListNewsletter() http.HandlerFunc {
 			return
 		}
 
-		resp, err := clientManager.GetWhatsmeowClient(userid).GetSubscribedNewsletters()
-
+		decoder := json.NewDecoder(r.Body)
+		var t newsletterMessageStruct
+		err := decoder.Decode(&t)
 		if err != nil {
-			msg := fmt.Sprintf("Failed to get newsletter list: %v", err)
-			log.Error().Msg(msg)
-			s.Respond(w, r, http.StatusInternalServerError, msg)
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode Payload"))
 			return
 		}
 
-		gc := new(NewsletterCollection)
-		gc.Newsletter = []types.NewsletterMetadata{}
-		for _, info := range resp {
-			gc.Newsletter = append(gc.Newsletter, *info)
+		if t.NewsletterJID == "" {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing NewsletterJID in Payload"))
+			return
 		}
 
-		responseJson, err := json.Marshal(gc)
+		newsletter, ok := parseJID(t.NewsletterJID)
+		if !ok || newsletter.Server != types.NewsletterServer {
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Could not parse Newsletter JID"))
+			return
+		}
+
+		var media string
+		var mediaType whatsmeow.MediaType
+		switch {
+		case t.Image != "" && t.Video != "":
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Image and Video cannot be sent together"))
+			return
+		case t.Image != "":
+			media, mediaType = t.Image, whatsmeow.MediaImage
+		case t.Video != "":
+			media, mediaType = t.Video, whatsmeow.MediaVideo
+		case t.Body == "":
+			s.Respond(w, r, http.StatusBadRequest, errors.New("Missing Body, Image or Video in Payload"))
+			return
+		}
+
+		client := clientManager.GetWhatsmeowClient(userid)
+
+		msgid := t.Id
+		if msgid == "" {
+			msgid = client.GenerateMessageID()
+		}
+
+		var msg *waE2E.Message
+		extra := whatsmeow.SendRequestExtra{ID: msgid}
+		if media != "" {
+			if !strings.HasPrefix(media, "data:") {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Media data should start with \"data:mime/type;base64,\""))
+				return
+			}
+			dataURL, err := dataurl.DecodeString(media)
+			if err != nil {
+				s.Respond(w, r, http.StatusBadRequest, errors.New("Could not decode base64 encoded data from payload"))
+				return
+			}
+			msg, extra.MediaHandle, err = uploadNewsletterMedia(client, dataURL.Data, mediaType, t.Caption)
+			if err != nil {
+				s.Respond(w, r, http.StatusInternalServerError, err)
+				return
+			}
+		} else {
+			msg = &waE2E.Message{
+				ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+					Text: &t.Body,
+				},
+			}
+		}
+
+		resp, err := client.SendMessage(context.Background(), newsletter, msg, extra)
+		if err != nil {
+			s.Respond(w, r, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error sending message: %v", err)))
+			return
+		}
+
+		log.Info().Str("timestamp", fmt.Sprintf("%v", resp.Timestamp)).Str("id", msgid).Str("newsletter", newsletter.String()).Msg("Newsletter message sent")
+		response := map[string]interface{}{"Details": "Sent", "Timestamp": resp.Timestamp, "Id": msgid, "ServerId": resp.ServerID}
+		responseJson, err := json.Marshal(response)
+
 		if err != nil {
 			s.Respond(w, r, http.StatusInternalServerError, err)
 		} else {
@@ -3355,6 +7337,9 @@ func (s *server) DeleteUser() http.HandlerFunc {
 			s.Respond(w, r, http.StatusNotFound, errors.New("User not found"))
 			return
 		}
+		if id, err := strconv.Atoi(userID); err == nil {
+			deletePresenceSubscriptions(s.db, id)
+		}
 
 		// Return a success response
 		response := map[string]interface{}{"Details": "User deleted successfully"}
@@ -3427,12 +7412,6 @@ func validateMessageFields(phone string, stanzaid *string, participant *string)
 		return types.NewJID("", types.DefaultUserServer), errors.New("Could not parse Phone")
 	}
 
-	if stanzaid != nil {
-		if participant == nil {
-			return types.NewJID("", types.DefaultUserServer), errors.New("Missing Participant in ContextInfo")
-		}
-	}
-
 	if participant != nil {
 		if stanzaid == nil {
 			return types.NewJID("", types.DefaultUserServer), errors.New("Missing StanzaID in ContextInfo")
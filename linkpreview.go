@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	linkPreviewTimeout      = 3 * time.Second
+	linkPreviewMaxPageSize  = 512 * 1024
+	linkPreviewMaxImageSize = 2 * 1024 * 1024
+	linkPreviewMaxRedirects = 5
+)
+
+// Ranges that are not routable on the internet on top of the ones the net package knows
+// about: carrier grade NAT and the IPv4/IPv6 translation and documentation prefixes
+var nonPublicNetworks = mustParseCIDRs("100.64.0.0/10", "192.0.0.0/24", "192.0.2.0/24", "198.18.0.0/15", "198.51.100.0/24", "203.0.113.0/24", "240.0.0.0/4", "64:ff9b::/96", "2001:db8::/32")
+
+// URLs in messages come from users, so previews are fetched with their own client that
+// refuses to connect to loopback, private or link-local addresses. The check runs on the
+// resolved address of every connection, redirects included. The user's proxy is not used,
+// pages are fetched directly from the server
+var linkPreviewClient = &http.Client{
+	Timeout: linkPreviewTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: linkPreviewTimeout,
+			Control: publicAddressOnly,
+		}).DialContext,
+		TLSHandshakeTimeout:   linkPreviewTimeout,
+		ResponseHeaderTimeout: linkPreviewTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= linkPreviewMaxRedirects {
+			return errors.New("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to unsupported scheme %s", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// Reports whether an address is reachable on the public internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Dialer hook rejecting connections to non public addresses, it runs after name resolution
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("connection to non public address %s refused", host)
+	}
+	return nil
+}
+
+var urlRegex = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+type linkPreview struct {
+	MatchedText   string
+	Title         string
+	Description   string
+	JPEGThumbnail []byte
+}
+
+// Returns the first http(s) URL found in a text, without trailing punctuation
+func findFirstURL(text string) string {
+	match := urlRegex.FindString(text)
+	return strings.TrimRight(match, ".,;:!?)]}")
+}
+
+// Reads at most limit bytes from the response body of a GET request
+func fetchLimited(ctx context.Context, client *http.Client, target string, limit int64) ([]byte, string, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, "", err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, "", fmt.Errorf("unsupported scheme %s", parsed.Scheme)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "WhatsApp/2.23 wuzapi")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// Extracts Open Graph title, description and image (falling back to <title> and
+// the description meta tag) from an HTML page
+func parseOpenGraph(page []byte) (title string, description string, imageURL string) {
+	var pageTitle, metaDescription string
+	tokenizer := html.NewTokenizer(bytes.NewReader(page))
+	inTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if title == "" {
+				title = strings.TrimSpace(pageTitle)
+			}
+			if description == "" {
+				description = metaDescription
+			}
+			return title, description, imageURL
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = true
+			case "meta":
+				var key, content string
+				for _, attr := range token.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "property", "name":
+						key = strings.ToLower(attr.Val)
+					case "content":
+						content = strings.TrimSpace(attr.Val)
+					}
+				}
+				switch key {
+				case "og:title":
+					title = content
+				case "og:description":
+					description = content
+				case "og:image", "og:image:url":
+					if imageURL == "" {
+						imageURL = content
+					}
+				case "description":
+					metaDescription = content
+				}
+			case "body":
+				// Open Graph tags live in the head, no need to go any further
+				if title == "" {
+					title = strings.TrimSpace(pageTitle)
+				}
+				if description == "" {
+					description = metaDescription
+				}
+				return title, description, imageURL
+			}
+		case html.TextToken:
+			if inTitle {
+				pageTitle += tokenizer.Token().Data
+			}
+		case html.EndTagToken:
+			if tokenizer.Token().Data == "title" {
+				inTitle = false
+			}
+		}
+	}
+}
+
+// Fetches the page behind a URL and builds the preview fields for an ExtendedTextMessage
+func fetchLinkPreview(link string) (*linkPreview, error) {
+	client := linkPreviewClient
+	ctx, cancel := context.WithTimeout(context.Background(), linkPreviewTimeout)
+	defer cancel()
+
+	page, contentType, err := fetchLimited(ctx, client, link, linkPreviewMaxPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s: %w", link, err)
+	}
+	if !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("%s is not an html page", link)
+	}
+
+	title, description, imageURL := parseOpenGraph(page)
+	if title == "" {
+		return nil, fmt.Errorf("no title found for %s", link)
+	}
+
+	preview := &linkPreview{
+		MatchedText: link,
+		Title:       title,
+		Description: description,
+	}
+
+	if imageURL != "" {
+		base, _ := url.Parse(link)
+		ref, err := url.Parse(imageURL)
+		if err == nil && base != nil {
+			imageURL = base.ResolveReference(ref).String()
+		}
+		data, _, err := fetchLimited(ctx, client, imageURL, linkPreviewMaxImageSize)
+		if err == nil {
+			if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+				preview.JPEGThumbnail, _ = jpegThumbnail(img)
+			}
+		}
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultLiveLocationDuration = time.Hour
+	maxLiveLocationDuration     = 8 * time.Hour
+)
+
+// Live location share started through the API. WhatsApp keeps a single live location per
+// sender and chat. Updates are edits of the message that started the share, carrying a
+// growing sequence number and the seconds elapsed since the share started, so recipients
+// move the existing bubble instead of getting a new one. Shares are kept in memory only
+// and are lost when wuzapi restarts
+type liveLocationShare struct {
+	Id             string
+	Chat           types.JID
+	Caption        string
+	StartedAt      time.Time
+	ExpiresAt      time.Time
+	SequenceNumber int64
+	Position       livePosition // last position sent
+}
+
+// Keeps, per user, the live location shares in progress keyed by chat
+type LiveLocationStore struct {
+	sync.Mutex
+	shares map[int]map[types.JID]*liveLocationShare
+}
+
+func NewLiveLocationStore() *LiveLocationStore {
+	return &LiveLocationStore{
+		shares: make(map[int]map[types.JID]*liveLocationShare),
+	}
+}
+
+func (ls *LiveLocationStore) Start(userID int, share *liveLocationShare) {
+	ls.Lock()
+	defer ls.Unlock()
+	if ls.shares[userID] == nil {
+		ls.shares[userID] = make(map[types.JID]*liveLocationShare)
+	}
+	ls.shares[userID][share.Chat.ToNonAD()] = share
+}
+
+// Returns a copy of the share in progress in a chat, expired shares are dropped
+func (ls *LiveLocationStore) Get(userID int, chat types.JID) (liveLocationShare, bool) {
+	ls.Lock()
+	defer ls.Unlock()
+	share, ok := ls.shares[userID][chat.ToNonAD()]
+	if !ok {
+		return liveLocationShare{}, false
+	}
+	if time.Now().After(share.ExpiresAt) {
+		delete(ls.shares[userID], chat.ToNonAD())
+		return liveLocationShare{}, false
+	}
+	return *share, true
+}
+
+// Moves the share forward to the given position and sequence number, or to the next
+// sequence number when it is 0
+func (ls *LiveLocationStore) Advance(userID int, chat types.JID, sequence int64, pos livePosition) (liveLocationShare, bool) {
+	ls.Lock()
+	defer ls.Unlock()
+	share, ok := ls.shares[userID][chat.ToNonAD()]
+	if !ok || time.Now().After(share.ExpiresAt) {
+		return liveLocationShare{}, false
+	}
+	if sequence <= share.SequenceNumber {
+		sequence = share.SequenceNumber + 1
+	}
+	share.SequenceNumber = sequence
+	share.Position = pos
+	return *share, true
+}
+
+func (ls *LiveLocationStore) Stop(userID int, chat types.JID) {
+	ls.Lock()
+	defer ls.Unlock()
+	delete(ls.shares[userID], chat.ToNonAD())
+}
+
+func (ls *LiveLocationStore) DeleteUser(userID int) {
+	ls.Lock()
+	defer ls.Unlock()
+	delete(ls.shares, userID)
+}
+
+// Position sent in a live location start or update
+type livePosition struct {
+	Latitude  float64
+	Longitude float64
+	Accuracy  uint32  // meters
+	Speed     float32 // meters per second
+	Heading   uint32  // degrees clockwise from magnetic north
+}
+
+func buildLiveLocation(share liveLocationShare) *waE2E.Message {
+	pos := share.Position
+	live := &waE2E.LiveLocationMessage{
+		DegreesLatitude:  proto.Float64(pos.Latitude),
+		DegreesLongitude: proto.Float64(pos.Longitude),
+		SequenceNumber:   proto.Int64(share.SequenceNumber),
+		TimeOffset:       proto.Uint32(uint32(time.Since(share.StartedAt).Seconds())),
+	}
+	if share.Caption != "" {
+		live.Caption = proto.String(share.Caption)
+	}
+	if pos.Accuracy > 0 {
+		live.AccuracyInMeters = proto.Uint32(pos.Accuracy)
+	}
+	if pos.Speed > 0 {
+		live.SpeedInMps = proto.Float32(pos.Speed)
+	}
+	if pos.Heading > 0 {
+		live.DegreesClockwiseFromMagneticNorth = proto.Uint32(pos.Heading % 360)
+	}
+	return &waE2E.Message{LiveLocationMessage: live}
+}
+
+// Builds an update of a share in progress, as an edit of the message that started it
+func buildLiveLocationUpdate(client *whatsmeow.Client, userID int, share liveLocationShare) *waE2E.Message {
+	live := buildLiveLocation(share)
+	applyExpiration(client, userID, share.Chat, live)
+	return client.BuildEdit(share.Chat, share.Id, live)
+}
+
+// Builds the last update of a share, with a time offset reaching the end of the share so
+// recipients see it as expired
+func buildLiveLocationStop(client *whatsmeow.Client, userID int, share liveLocationShare) *waE2E.Message {
+	live := buildLiveLocation(share)
+	live.LiveLocationMessage.TimeOffset = proto.Uint32(uint32(share.ExpiresAt.Sub(share.StartedAt).Seconds()))
+	applyExpiration(client, userID, share.Chat, live)
+	return client.BuildEdit(share.Chat, share.Id, live)
+}
+
+// Live location received from a contact, sent in Location webhooks
+type locationUpdate struct {
+	Chat           types.JID
+	Sender         types.JID
+	Id             string
+	Latitude       float64
+	Longitude      float64
+	Accuracy       uint32  `json:",omitempty"`
+	Speed          float32 `json:",omitempty"`
+	Heading        uint32  `json:",omitempty"`
+	Caption        string  `json:",omitempty"`
+	SequenceNumber int64
+	TimeOffset     uint32
+	Timestamp      time.Time
+}
+
+// Converts an incoming live location message, or an edit updating one, to a
+// locationUpdate, returns nil for anything else. Id is the id of the message that
+// started the share
+func parseLiveLocation(evt *events.Message) *locationUpdate {
+	id := evt.Info.ID
+	live := evt.Message.GetLiveLocationMessage()
+	if protocol := evt.Message.GetProtocolMessage(); protocol.GetType() == waE2E.ProtocolMessage_MESSAGE_EDIT {
+		id = protocol.GetKey().GetID()
+		live = protocol.GetEditedMessage().GetLiveLocationMessage()
+	}
+	if live == nil {
+		return nil
+	}
+	return &locationUpdate{
+		Chat:           evt.Info.Chat,
+		Sender:         evt.Info.Sender,
+		Id:             id,
+		Latitude:       live.GetDegreesLatitude(),
+		Longitude:      live.GetDegreesLongitude(),
+		Accuracy:       live.GetAccuracyInMeters(),
+		Speed:          live.GetSpeedInMps(),
+		Heading:        live.GetDegreesClockwiseFromMagneticNorth(),
+		Caption:        live.GetCaption(),
+		SequenceNumber: live.GetSequenceNumber(),
+		TimeOffset:     live.GetTimeOffset(),
+		Timestamp:      evt.Info.Timestamp,
+	}
+}
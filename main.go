@@ -32,21 +32,26 @@ type server struct {
 
 // Replace the global variables
 var (
-	address     = flag.String("address", "0.0.0.0", "Bind IP Address")
-	port        = flag.String("port", "8080", "Listen Port")
-	waDebug     = flag.String("wadebug", "", "Enable whatsmeow debug (INFO or DEBUG)")
-	logType     = flag.String("logtype", "console", "Type of log output (console or json)")
-	skipMedia   = flag.Bool("skipmedia", false, "Do not attempt to download media in messages)")
-	osName      = flag.String("osname", "Mac OS 10", "Connection OSName in Whatsapp")
-	colorOutput = flag.Bool("color", false, "Enable colored output for console logs")
-	sslcert     = flag.String("sslcertificate", "", "SSL Certificate File")
-	sslprivkey  = flag.String("sslprivatekey", "", "SSL Certificate Private Key File")
-	adminToken  = flag.String("admintoken", "", "Security Token to authorize admin actions (list/create/remove users)")
-
-	container     *sqlstore.Container
-	clientManager = NewClientManager()
-	killchannel   = make(map[int](chan bool))
-	userinfocache = cache.New(5*time.Minute, 10*time.Minute)
+	address        = flag.String("address", "0.0.0.0", "Bind IP Address")
+	port           = flag.String("port", "8080", "Listen Port")
+	waDebug        = flag.String("wadebug", "", "Enable whatsmeow debug (INFO or DEBUG)")
+	logType        = flag.String("logtype", "console", "Type of log output (console or json)")
+	skipMedia      = flag.Bool("skipmedia", false, "Do not attempt to download media in messages)")
+	publicURL      = flag.String("publicurl", "", "Public URL of this server, used in links to hosted media (e.g. https://wuzapi.example.com)")
+	osName         = flag.String("osname", "Mac OS 10", "Connection OSName in Whatsapp")
+	colorOutput    = flag.Bool("color", false, "Enable colored output for console logs")
+	sslcert        = flag.String("sslcertificate", "", "SSL Certificate File")
+	sslprivkey     = flag.String("sslprivatekey", "", "SSL Certificate Private Key File")
+	adminToken     = flag.String("admintoken", "", "Security Token to authorize admin actions (list/create/remove users)")
+	defaultCountry = flag.String("defaultcountry", "", "Country calling code added to phone numbers in national format when checking numbers (e.g. 55)")
+
+	container         *sqlstore.Container
+	clientManager     = NewClientManager()
+	killchannel       = make(map[int](chan bool))
+	userinfocache     = cache.New(5*time.Minute, 10*time.Minute)
+	presenceStore     = NewPresenceStore()
+	ephemeralStore    = NewEphemeralStore()
+	liveLocationStore = NewLiveLocationStore()
 )
 
 func init() {
@@ -112,6 +117,11 @@ func init() {
 			*adminToken = v
 		}
 	}
+
+	*defaultCountry = strings.TrimPrefix(*defaultCountry, "+")
+	if *defaultCountry != "" && !validCountryCode(*defaultCountry) {
+		log.Fatal().Str("defaultcountry", *defaultCountry).Msg("Invalid -defaultcountry, it must be a country calling code made of 1 to 3 digits")
+	}
 }
 
 func main() {
@@ -167,6 +177,8 @@ func main() {
 
 	s.connectOnStartup()
 
+	go cleanupHostedMedia(exPath)
+
 	srv := &http.Server{
 		Addr:              *address + ":" + *port,
 		Handler:           s.router,
@@ -237,7 +249,10 @@ func initializeSchema(db *sqlx.DB) error {
 
 	if exists {
 		log.Info().Msg("Users table already exists")
-		return nil
+		if err := migrateUsersTable(db); err != nil {
+			return err
+		}
+		return createPresenceSubscriptionsTable(db)
 	}
 	// Create table statement that works with both PostgreSQL and SQLite
 	var sqlStmt string
@@ -252,7 +267,11 @@ func initializeSchema(db *sqlx.DB) error {
             connected INTEGER,
             expiration INTEGER,
             events TEXT NOT NULL DEFAULT 'All',
-            proxy_url TEXT DEFAULT ''
+            proxy_url TEXT DEFAULT '',
+            media_delivery TEXT NOT NULL DEFAULT 'base64',
+            media_types TEXT NOT NULL DEFAULT '',
+            media_max_size INTEGER NOT NULL DEFAULT 0,
+            default_ephemeral INTEGER NOT NULL DEFAULT 0
         );`
 	} else {
 		// SQLite version
@@ -266,7 +285,11 @@ func initializeSchema(db *sqlx.DB) error {
             connected INTEGER,
             expiration INTEGER,
             events TEXT NOT NULL DEFAULT 'All',
-            proxy_url TEXT DEFAULT ''
+            proxy_url TEXT DEFAULT '',
+            media_delivery TEXT NOT NULL DEFAULT 'base64',
+            media_types TEXT NOT NULL DEFAULT '',
+            media_max_size INTEGER NOT NULL DEFAULT 0,
+            default_ephemeral INTEGER NOT NULL DEFAULT 0
         );`
 	}
 
@@ -277,5 +300,66 @@ func initializeSchema(db *sqlx.DB) error {
 	}
 
 	log.Info().Msg("Successfully created users table")
+	return createPresenceSubscriptionsTable(db)
+}
+
+// Columns added to the users table after it was first released, created on existing
+// databases at startup
+var usersTableMigrations = []struct {
+	column     string
+	definition string
+}{
+	{"media_delivery", "TEXT NOT NULL DEFAULT 'base64'"},
+	{"media_types", "TEXT NOT NULL DEFAULT ''"},
+	{"media_max_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"default_ephemeral", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+func migrateUsersTable(db *sqlx.DB) error {
+	for _, migration := range usersTableMigrations {
+		var exists bool
+		var err error
+		if db.DriverName() == "postgres" {
+			err = db.Get(&exists, `
+            SELECT EXISTS (
+                SELECT 1
+                FROM information_schema.columns
+                WHERE table_name = 'users' AND column_name = $1
+            );`, migration.column)
+		} else {
+			err = db.Get(&exists, `
+            SELECT EXISTS (
+                SELECT 1
+                FROM pragma_table_info('users')
+                WHERE name = $1
+            );`, migration.column)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("column", migration.column).Msg("Failed to check users table column")
+			return err
+		}
+		if exists {
+			continue
+		}
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE users ADD COLUMN %s %s", migration.column, migration.definition))
+		if err != nil {
+			log.Error().Err(err).Str("column", migration.column).Msg("Failed to add users table column")
+			return err
+		}
+		log.Info().Str("column", migration.column).Msg("Added column to users table")
+	}
 	return nil
 }
+
+// Contacts each user subscribed to the presence of, restored when the user connects
+func createPresenceSubscriptionsTable(db *sqlx.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS presence_subscriptions (
+            user_id INTEGER NOT NULL,
+            jid TEXT NOT NULL,
+            PRIMARY KEY (user_id, jid)
+        );`)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create presence_subscriptions table")
+	}
+	return err
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/nfnt/resize"
+	"github.com/rs/zerolog/log"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// Time allowed for ffmpeg/ffprobe to process a single media file
+const ffmpegTimeout = 30 * time.Second
+
+type videoInfo struct {
+	Seconds uint32
+	Width   uint32
+	Height  uint32
+}
+
+// Returns a 72px JPEG thumbnail for the given image, preserving aspect ratio
+func jpegThumbnail(img image.Image) ([]byte, error) {
+	m := resize.Thumbnail(72, 72, img, resize.Lanczos3)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, m, nil); err != nil {
+		return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Size in pixels of the profile pictures we upload, the same WhatsApp uses for its own
+const profilePictureSize = 640
+
+// Crops an image to a centered square and returns it as a 640px JPEG suitable for
+// a profile picture
+func profilePicture(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	for py := 0; py < side; py++ {
+		for px := 0; px < side; px++ {
+			square.Set(px, py, img.At(x+px, y+py))
+		}
+	}
+
+	size := uint(profilePictureSize)
+	if side < profilePictureSize {
+		size = uint(side)
+	}
+	m := resize.Resize(size, size, square, resize.Lanczos3)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, m, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Writes media data to a temporary file so it can be handed to ffmpeg/ffprobe
+func writeTempMedia(data []byte, pattern string) (string, error) {
+	tmpFile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("could not create temp file: %w", err)
+	}
+	defer tmpFile.Close()
+	if _, err := tmpFile.Write(data); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("could not write temp file: %w", err)
+	}
+	return tmpFile.Name(), nil
+}
+
+// Gets duration and dimensions of a video using ffprobe
+func probeVideo(data []byte) (videoInfo, error) {
+	var info videoInfo
+
+	ffprobe, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return info, errors.New("ffprobe not available")
+	}
+
+	inPath, err := writeTempMedia(data, "video-*.mp4")
+	if err != nil {
+		return info, err
+	}
+	defer os.Remove(inPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), ffmpegTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, ffprobe,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		inPath,
+	).Output()
+	if err != nil {
+		return info, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe struct {
+		Streams []struct {
+			Width  uint32 `json:"width"`
+			Height uint32 `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return info, fmt.Errorf("could not parse ffprobe output: %w", err)
+	}
+
+	if len(probe.Streams) > 0 {
+		info.Width = probe.Streams[0].Width
+		info.Height = probe.Streams[0].Height
+	}
+	if duration, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil && duration > 0 {
+		info.Seconds = uint32(math.Ceil(duration))
+	}
+	return info, nil
+}
+
+// Extracts a frame from a video using ffmpeg and returns it as a JPEG thumbnail
+func videoThumbnail(data []byte, seconds uint32) ([]byte, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, errors.New("ffmpeg not available")
+	}
+
+	inPath, err := writeTempMedia(data, "video-*.mp4")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(inPath)
+
+	outPath := filepath.Join(os.TempDir(), filepath.Base(inPath)+".jpg")
+	defer os.Remove(outPath)
+
+	// Grab a frame one second in, or the first frame for very short videos
+	offset := "0"
+	if seconds > 1 {
+		offset = "1"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ffmpegTimeout)
+	defer cancel()
+
+	err = exec.CommandContext(ctx, ffmpeg,
+		"-y",
+		"-loglevel", "error",
+		"-ss", offset,
+		"-i", inPath,
+		"-frames:v", "1",
+		outPath,
+	).Run()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+
+	frame, err := os.ReadFile(outPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read extracted frame: %w", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(frame))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode extracted frame: %w", err)
+	}
+	return jpegThumbnail(img)
+}
+
+// Uploads an image and builds the message with a JPEG thumbnail
+func uploadImageMessage(client *whatsmeow.Client, filedata []byte, caption string) (*waE2E.ImageMessage, error) {
+	uploaded, err := client.Upload(context.Background(), filedata, whatsmeow.MediaImage)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to upload file: %v", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(filedata))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode image for thumbnail preparation: %v", err)
+	}
+	thumbnailBytes, err := jpegThumbnail(img)
+	if err != nil {
+		return nil, err
+	}
+
+	return &waE2E.ImageMessage{
+		Caption:       proto.String(caption),
+		URL:           proto.String(uploaded.URL),
+		DirectPath:    proto.String(uploaded.DirectPath),
+		MediaKey:      uploaded.MediaKey,
+		Mimetype:      proto.String(http.DetectContentType(filedata)),
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    proto.Uint64(uint64(len(filedata))),
+		JPEGThumbnail: thumbnailBytes,
+	}, nil
+}
+
+// Uploads a video and builds the message. Duration, dimensions and a thumbnail frame
+// are extracted when ffmpeg is available, otherwise the supplied thumbnail is used
+func uploadVideoMessage(client *whatsmeow.Client, filedata []byte, caption string, thumbnail []byte) (*waE2E.VideoMessage, error) {
+	uploaded, err := client.Upload(context.Background(), filedata, whatsmeow.MediaVideo)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to upload file: %v", err)
+	}
+
+	info, err := probeVideo(filedata)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not probe video")
+	}
+	thumbnailBytes, err := videoThumbnail(filedata, info.Seconds)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not extract video thumbnail")
+		thumbnailBytes = thumbnail
+	}
+
+	video := &waE2E.VideoMessage{
+		Caption:       proto.String(caption),
+		URL:           proto.String(uploaded.URL),
+		DirectPath:    proto.String(uploaded.DirectPath),
+		MediaKey:      uploaded.MediaKey,
+		Mimetype:      proto.String(http.DetectContentType(filedata)),
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    proto.Uint64(uint64(len(filedata))),
+		JPEGThumbnail: thumbnailBytes,
+	}
+	if info.Seconds > 0 {
+		video.Seconds = proto.Uint32(info.Seconds)
+	}
+	if info.Width > 0 && info.Height > 0 {
+		video.Width = proto.Uint32(info.Width)
+		video.Height = proto.Uint32(info.Height)
+	}
+	return video, nil
+}
+
+const (
+	stickerSize            = 512
+	stickerMaxStaticSize   = 100 * 1024
+	stickerMaxAnimatedSize = 500 * 1024
+)
+
+// Sticker pack metadata embedded in the WebP EXIF chunk
+type stickerMetadata struct {
+	PackID    string   `json:"sticker-pack-id"`
+	PackName  string   `json:"sticker-pack-name,omitempty"`
+	Publisher string   `json:"sticker-pack-publisher,omitempty"`
+	Emojis    []string `json:"emojis,omitempty"`
+}
+
+// Checks whether a GIF has more than one frame
+func isAnimatedGIF(data []byte) bool {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	return len(g.Image) > 1
+}
+
+// Checks the extended header of a WebP file for the animation flag
+func isAnimatedWebP(data []byte) bool {
+	return len(data) > 20 && string(data[12:16]) == "VP8X" && data[20]&0x02 != 0
+}
+
+// Derives a stable pack id so stickers with the same pack name and publisher are grouped
+func stickerPackID(name string, publisher string) string {
+	sum := sha256.Sum256([]byte(name + "\x00" + publisher))
+	return "wuzapi." + hex.EncodeToString(sum[:8])
+}
+
+// Converts PNG, JPEG or GIF data into a 512x512 WebP sticker with transparent
+// padding, lowering quality until it fits WhatsApp's size limits
+func convertToSticker(data []byte, animated bool) ([]byte, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, errors.New("ffmpeg is required to convert images to stickers")
+	}
+
+	inPath, err := writeTempMedia(data, "sticker-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(inPath)
+
+	outPath := filepath.Join(os.TempDir(), filepath.Base(inPath)+".webp")
+	defer os.Remove(outPath)
+
+	maxSize := stickerMaxStaticSize
+	if animated {
+		maxSize = stickerMaxAnimatedSize
+	}
+
+	filter := fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,format=rgba,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=0x00000000",
+		stickerSize, stickerSize, stickerSize, stickerSize)
+
+	for _, quality := range []int{80, 60, 40, 20} {
+		args := []string{"-y", "-loglevel", "error", "-i", inPath}
+		if animated {
+			args = append(args, "-vf", "fps=15,"+filter, "-t", "10", "-loop", "0")
+		} else {
+			args = append(args, "-vf", filter, "-frames:v", "1")
+		}
+		args = append(args, "-c:v", "libwebp", "-lossless", "0", "-q:v", strconv.Itoa(quality), "-an", outPath)
+
+		ctx, cancel := context.WithTimeout(context.Background(), ffmpegTimeout)
+		err = exec.CommandContext(ctx, ffmpeg, args...).Run()
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg failed: %w", err)
+		}
+
+		webp, err := os.ReadFile(outPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read converted sticker: %w", err)
+		}
+		if len(webp) <= maxSize {
+			return webp, nil
+		}
+		log.Debug().Int("quality", quality).Int("size", len(webp)).Msg("Sticker too large, retrying with lower quality")
+	}
+
+	return nil, fmt.Errorf("sticker exceeds maximum size of %d bytes", maxSize)
+}
+
+// Builds the EXIF payload WhatsApp reads sticker pack information from
+func stickerExif(meta stickerMetadata) ([]byte, error) {
+	jsonData, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	// Little endian TIFF header with a single IFD entry: tag 0x5741, type UNDEFINED,
+	// pointing to the JSON data right after the IFD
+	exif := []byte{0x49, 0x49, 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00, 0x41, 0x57, 0x07, 0x00, 0, 0, 0, 0, 0x16, 0x00, 0x00, 0x00}
+	binary.LittleEndian.PutUint32(exif[14:18], uint32(len(jsonData)))
+	return append(exif, jsonData...), nil
+}
+
+// Embeds sticker pack metadata into a WebP file as an EXIF chunk, converting
+// simple WebP files to the extended (VP8X) format when needed
+func setStickerMetadata(webp []byte, meta stickerMetadata) ([]byte, error) {
+	if len(webp) < 12 || string(webp[0:4]) != "RIFF" || string(webp[8:12]) != "WEBP" {
+		return nil, errors.New("not a webp file")
+	}
+
+	type chunk struct {
+		fourcc string
+		data   []byte
+	}
+
+	var chunks []chunk
+	for pos := 12; pos+8 <= len(webp); {
+		size := int(binary.LittleEndian.Uint32(webp[pos+4 : pos+8]))
+		end := pos + 8 + size
+		if end > len(webp) {
+			return nil, errors.New("truncated webp chunk")
+		}
+		fourcc := string(webp[pos : pos+4])
+		if fourcc != "EXIF" {
+			chunks = append(chunks, chunk{fourcc, webp[pos+8 : end]})
+		}
+		pos = end + size%2
+	}
+	if len(chunks) == 0 {
+		return nil, errors.New("empty webp file")
+	}
+
+	exif, err := stickerExif(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if chunks[0].fourcc != "VP8X" {
+		// Simple format files have a single VP8/VP8L chunk; wrap it in an extended header
+		vp8x := make([]byte, 10)
+		if chunks[0].fourcc == "VP8L" {
+			vp8x[0] |= 0x10
+		}
+		width, height, err := webpDimensions(chunks[0].fourcc, chunks[0].data)
+		if err != nil {
+			return nil, err
+		}
+		putUint24(vp8x[4:7], uint32(width-1))
+		putUint24(vp8x[7:10], uint32(height-1))
+		chunks = append([]chunk{{"VP8X", vp8x}}, chunks...)
+	}
+	flags := append([]byte{}, chunks[0].data...)
+	flags[0] |= 0x08
+	chunks[0].data = flags
+	chunks = append(chunks, chunk{"EXIF", exif})
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, c := range chunks {
+		body.WriteString(c.fourcc)
+		binary.Write(&body, binary.LittleEndian, uint32(len(c.data)))
+		body.Write(c.data)
+		if len(c.data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// Checks that a WebP file has the sticker dimensions and fits the size limit
+func validStickerWebP(data []byte, animated bool) bool {
+	maxSize := stickerMaxStaticSize
+	if animated {
+		maxSize = stickerMaxAnimatedSize
+	}
+	if len(data) > maxSize || len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return false
+	}
+	var width, height int
+	if fourcc := string(data[12:16]); fourcc == "VP8X" {
+		// Extended format files carry the canvas size in their header
+		width = int(uint32(data[24])|uint32(data[25])<<8|uint32(data[26])<<16) + 1
+		height = int(uint32(data[27])|uint32(data[28])<<8|uint32(data[29])<<16) + 1
+	} else {
+		var err error
+		width, height, err = webpDimensions(fourcc, data[20:])
+		if err != nil {
+			return false
+		}
+	}
+	return width == stickerSize && height == stickerSize
+}
+
+// Reads canvas dimensions from a simple format VP8 or VP8L bitstream
+func webpDimensions(fourcc string, data []byte) (int, int, error) {
+	switch fourcc {
+	case "VP8 ":
+		if len(data) < 10 || data[3] != 0x9d || data[4] != 0x01 || data[5] != 0x2a {
+			return 0, 0, errors.New("invalid VP8 bitstream")
+		}
+		width := int(binary.LittleEndian.Uint16(data[6:8]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(data[8:10]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		if len(data) < 5 || data[0] != 0x2f {
+			return 0, 0, errors.New("invalid VP8L bitstream")
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	}
+	return 0, 0, fmt.Errorf("unexpected webp chunk %q", fourcc)
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// How incoming media is delivered in webhooks
+const (
+	mediaDeliveryBase64    = "base64"    // downloaded and sent inline, base64 encoded
+	mediaDeliveryReference = "reference" // only the keys and paths needed to download it later
+	mediaDeliveryURL       = "url"       // downloaded and served by wuzapi under /media
+)
+
+// Hosted media files are removed after this long
+const hostedMediaTTL = 24 * time.Hour
+
+var mediaDeliveryModes = []string{mediaDeliveryBase64, mediaDeliveryReference, mediaDeliveryURL}
+var mediaDeliveryTypes = []string{"image", "audio", "video", "document", "sticker"}
+
+// Names of hosted media files are message ids plus an extension. Message ids are chosen
+// by the sender, so anything else is refused before it reaches the file system
+var hostedMediaName = regexp.MustCompile(`^[A-Za-z0-9]+\.[A-Za-z0-9]+$`)
+
+// Per user media delivery settings. Media of a type not listed in Types, or bigger than
+// MaxSize bytes, is delivered by reference. No Types means all types, no MaxSize no limit
+type mediaDeliveryConfig struct {
+	Mode    string
+	Types   []string
+	MaxSize uint64
+}
+
+// Reads the media delivery settings kept in the user info cache
+func mediaDeliveryFromUserInfo(userinfo Values) mediaDeliveryConfig {
+	config := mediaDeliveryConfig{Mode: userinfo.Get("MediaDelivery")}
+	if config.Mode == "" {
+		config.Mode = mediaDeliveryBase64
+	}
+	if types := userinfo.Get("MediaTypes"); types != "" {
+		config.Types = strings.Split(types, ",")
+	}
+	config.MaxSize, _ = strconv.ParseUint(userinfo.Get("MediaMaxSize"), 10, 64)
+	return config
+}
+
+// Returns the delivery mode to use for a media of the given type and size
+func (config mediaDeliveryConfig) modeFor(kind string, size uint64) string {
+	if len(config.Types) > 0 && !Find(config.Types, kind) {
+		return mediaDeliveryReference
+	}
+	if config.MaxSize > 0 && size > config.MaxSize {
+		return mediaDeliveryReference
+	}
+	return config.Mode
+}
+
+// Media message types carry these on top of what is needed to download them
+type mediaMessage interface {
+	whatsmeow.DownloadableMessage
+	GetURL() string
+	GetMimetype() string
+	GetFileLength() uint64
+}
+
+// Media received in a message, sent in webhooks as "media". The fields match the ones
+// expected by the /chat/download endpoints
+type incomingMedia struct {
+	Type          string
+	Delivery      string
+	Mimetype      string
+	FileName      string `json:",omitempty"`
+	FileLength    uint64
+	Url           string
+	DirectPath    string
+	MediaKey      []byte
+	FileSHA256    []byte
+	FileEncSHA256 []byte
+	HostedUrl     string `json:",omitempty"`
+}
+
+// Returns the media of a message with its type, nil when it has none
+func findMedia(msg *waE2E.Message) (mediaMessage, string) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage(), "image"
+	case msg.GetAudioMessage() != nil:
+		return msg.GetAudioMessage(), "audio"
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage(), "video"
+	case msg.GetPtvMessage() != nil:
+		return msg.GetPtvMessage(), "video"
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage(), "document"
+	case msg.GetStickerMessage() != nil:
+		return msg.GetStickerMessage(), "sticker"
+	}
+	return nil, ""
+}
+
+// File extension for a media, from its mimetype or, for documents, its file name
+func mediaExtension(media mediaMessage, kind string) string {
+	if exts, err := mime.ExtensionsByType(media.GetMimetype()); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	if document, ok := media.(*waE2E.DocumentMessage); ok && hostedMediaName.MatchString("file"+filepath.Ext(document.GetFileName())) {
+		return filepath.Ext(document.GetFileName())
+	}
+	switch kind {
+	case "audio":
+		return ".ogg"
+	case "sticker":
+		return ".webp"
+	}
+	return ".bin"
+}
+
+func hostedMediaDirectory(exPath string, userID int) string {
+	return filepath.Join(exPath, "media", "user_"+strconv.Itoa(userID))
+}
+
+// Adds the media of an incoming message to the webhook payload, following the media
+// delivery settings of the user. Inline media also fills the base64, mimeType and fileName
+// fields webhooks had before delivery modes existed
+func (mycli *MyClient) addIncomingMedia(postmap map[string]interface{}, msgID string, msg *waE2E.Message) {
+	media, kind := findMedia(msg)
+	if media == nil {
+		return
+	}
+
+	var config mediaDeliveryConfig
+	if userinfo, found := userinfocache.Get(mycli.token); found {
+		config = mediaDeliveryFromUserInfo(userinfo.(Values))
+	} else {
+		config = mediaDeliveryConfig{Mode: mediaDeliveryBase64}
+	}
+
+	info := &incomingMedia{
+		Type:          kind,
+		Delivery:      config.modeFor(kind, media.GetFileLength()),
+		Mimetype:      media.GetMimetype(),
+		FileLength:    media.GetFileLength(),
+		Url:           media.GetURL(),
+		DirectPath:    media.GetDirectPath(),
+		MediaKey:      media.GetMediaKey(),
+		FileSHA256:    media.GetFileSHA256(),
+		FileEncSHA256: media.GetFileEncSHA256(),
+	}
+	if document, ok := media.(*waE2E.DocumentMessage); ok {
+		info.FileName = document.GetFileName()
+	}
+	postmap["media"] = info
+
+	// Media downloads can be disabled for the whole server
+	if *skipMedia {
+		info.Delivery = mediaDeliveryReference
+	}
+	if info.Delivery == mediaDeliveryReference {
+		return
+	}
+
+	// The mode may have been set while the server ran with a public URL, links without
+	// it cannot be resolved by webhook consumers
+	if info.Delivery == mediaDeliveryURL && *publicURL == "" {
+		log.Warn().Str("id", msgID).Msg("No public URL configured, delivering hosted media inline")
+		info.Delivery = mediaDeliveryBase64
+	}
+
+	fileName := msgID + mediaExtension(media, kind)
+
+	switch info.Delivery {
+	case mediaDeliveryBase64:
+		data, err := mycli.WAClient.Download(media)
+		if err != nil {
+			log.Error().Err(err).Str("type", kind).Msg("Failed to download media, delivering by reference")
+			info.Delivery = mediaDeliveryReference
+			return
+		}
+		postmap["base64"] = base64.StdEncoding.EncodeToString(data)
+		postmap["mimeType"] = http.DetectContentType(data)
+		postmap["fileName"] = fileName
+		log.Info().Str("type", kind).Str("delivery", info.Delivery).Int("size", len(data)).Msg("Media added to webhook")
+	case mediaDeliveryURL:
+		if !hostedMediaName.MatchString(fileName) {
+			log.Warn().Str("id", msgID).Msg("Message id cannot be used as a file name, delivering media by reference")
+			info.Delivery = mediaDeliveryReference
+			return
+		}
+		// The webhook goes out right away, the link works once the download finished
+		info.HostedUrl = strings.TrimSuffix(*publicURL, "/") + "/media/" + fileName
+		go mycli.hostMedia(media, kind, fileName)
+	}
+}
+
+// Downloads a media and saves it under the hosted media directory of the user. The file
+// is written under a temporary name first so it is never served half written
+func (mycli *MyClient) hostMedia(media mediaMessage, kind string, fileName string) {
+	data, err := mycli.WAClient.Download(media)
+	if err != nil {
+		log.Error().Err(err).Str("type", kind).Str("file", fileName).Msg("Failed to download media to host")
+		return
+	}
+	directory := hostedMediaDirectory(mycli.exPath, mycli.userID)
+	if err := os.MkdirAll(directory, 0751); err != nil {
+		log.Error().Err(err).Msg("Could not create media directory")
+		return
+	}
+	file, err := os.CreateTemp(directory, ".download-*")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to save media")
+		return
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(directory, fileName))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		log.Error().Err(err).Msg("Failed to save media")
+		return
+	}
+	log.Info().Str("type", kind).Str("file", fileName).Int("size", len(data)).Msg("Media hosted")
+}
+
+// Opens a hosted media file of a user, names with path elements are rejected
+func openHostedMedia(exPath string, userID int, name string) (*os.File, error) {
+	if !hostedMediaName.MatchString(name) {
+		return nil, errors.New("Invalid media name")
+	}
+	return os.Open(filepath.Join(hostedMediaDirectory(exPath, userID), name))
+}
+
+// Periodically removes hosted media files older than hostedMediaTTL
+func cleanupHostedMedia(exPath string) {
+	for {
+		users, _ := filepath.Glob(filepath.Join(exPath, "media", "user_*"))
+		for _, directory := range users {
+			files, err := os.ReadDir(directory)
+			if err != nil {
+				continue
+			}
+			for _, file := range files {
+				info, err := file.Info()
+				if err != nil || time.Since(info.ModTime()) < hostedMediaTTL {
+					continue
+				}
+				if err := os.Remove(filepath.Join(directory, file.Name())); err != nil {
+					log.Warn().Err(err).Str("file", file.Name()).Msg("Could not remove hosted media")
+				}
+			}
+		}
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/proto/waWeb"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// @number tokens in a message body, as typed on the phone
+var mentionRegex = regexp.MustCompile(`(?:^|[^\w@])@(\d{6,15})\b`)
+
+// Recent incoming and outgoing messages, used to render quoted replies
+var messagecache = cache.New(24*time.Hour, time.Hour)
+
+type cachedMessage struct {
+	Chat    types.JID
+	Sender  types.JID
+	Message *waE2E.Message
+}
+
+func messageCacheKey(userid int, msgid string) string {
+	return fmt.Sprintf("%d:%s", userid, msgid)
+}
+
+// Last message of a chat. Patches acting on a whole chat (read, clear, delete) carry its
+// key so other devices know up to which message the action applies
+type lastChatMessage struct {
+	ID        string
+	Sender    types.JID
+	Timestamp time.Time
+}
+
+func lastMessageCacheKey(userid int, chat types.JID) string {
+	return fmt.Sprintf("%d:chat:%s", userid, chat.ToNonAD())
+}
+
+// Stores a message so later replies can quote its real content
+func rememberMessage(userid int, msgid string, chat types.JID, sender types.JID, msg *waE2E.Message) {
+	rememberMessageAt(userid, msgid, chat, sender, msg, time.Now())
+}
+
+// Same as rememberMessage for messages whose timestamp is known, e.g. incoming ones
+func rememberMessageAt(userid int, msgid string, chat types.JID, sender types.JID, msg *waE2E.Message, timestamp time.Time) {
+	if msg == nil || msgid == "" {
+		return
+	}
+	messagecache.Set(messageCacheKey(userid, msgid), cachedMessage{
+		Chat:    chat,
+		Sender:  sender.ToNonAD(),
+		Message: msg,
+	}, cache.DefaultExpiration)
+	// Reactions, edits and revokes are not messages of their own in the chat
+	if msg.ReactionMessage == nil && msg.ProtocolMessage == nil {
+		rememberLastMessage(userid, chat, lastChatMessage{ID: msgid, Sender: sender.ToNonAD(), Timestamp: timestamp})
+	}
+}
+
+// Keeps the newest message seen in a chat. Unlike messages, it does not expire so chats
+// stay manageable however long they have been quiet
+func rememberLastMessage(userid int, chat types.JID, last lastChatMessage) {
+	if current, found := recallLastMessage(userid, chat); found && current.Timestamp.After(last.Timestamp) {
+		return
+	}
+	messagecache.Set(lastMessageCacheKey(userid, chat), last, cache.NoExpiration)
+}
+
+// Learns the last message of every chat in a history sync
+func rememberHistoryLastMessages(userid int, ownID types.JID, conversations []*waHistorySync.Conversation) {
+	for _, conv := range conversations {
+		chat, err := types.ParseJID(conv.GetID())
+		if err != nil {
+			continue
+		}
+		var newest *waWeb.WebMessageInfo
+		for _, item := range conv.GetMessages() {
+			info := item.GetMessage()
+			if info.GetKey().GetID() == "" || info.GetMessage() == nil {
+				continue
+			}
+			if newest == nil || info.GetMessageTimestamp() > newest.GetMessageTimestamp() {
+				newest = info
+			}
+		}
+		if newest == nil {
+			continue
+		}
+		sender := chat
+		if newest.GetKey().GetFromMe() {
+			sender = ownID
+		} else if participant, err := types.ParseJID(newest.GetKey().GetParticipant()); err == nil && newest.GetKey().GetParticipant() != "" {
+			sender = participant
+		}
+		rememberLastMessage(userid, chat, lastChatMessage{
+			ID:        newest.GetKey().GetID(),
+			Sender:    sender.ToNonAD(),
+			Timestamp: time.Unix(int64(newest.GetMessageTimestamp()), 0),
+		})
+	}
+}
+
+// Returns the newest message seen in a chat since wuzapi started
+func recallLastMessage(userid int, chat types.JID) (lastChatMessage, bool) {
+	item, found := messagecache.Get(lastMessageCacheKey(userid, chat))
+	if !found {
+		return lastChatMessage{}, false
+	}
+	return item.(lastChatMessage), true
+}
+
+// Looks up a recent message by id
+func recallMessage(userid int, msgid string) (cachedMessage, bool) {
+	item, found := messagecache.Get(messageCacheKey(userid, msgid))
+	if !found {
+		return cachedMessage{}, false
+	}
+	return item.(cachedMessage), true
+}
+
+// Builds the key of a message in a chat. Ids of our own messages are prefixed with
+// "me:", as in reactions. In groups the sender of other people's messages is the given
+// participant or, when empty, taken from the recent messages cache
+func buildMessageKey(userid int, chat types.JID, msgid string, participant string) (*waCommon.MessageKey, error) {
+	fromMe := false
+	if strings.HasPrefix(msgid, "me:") {
+		fromMe = true
+		msgid = msgid[len("me:"):]
+	}
+	key := &waCommon.MessageKey{
+		RemoteJID: proto.String(chat.String()),
+		FromMe:    proto.Bool(fromMe),
+		ID:        proto.String(msgid),
+	}
+	if !fromMe && chat.Server == types.GroupServer {
+		if participant != "" {
+			jid, ok := parseJID(participant)
+			if !ok {
+				return nil, errors.New("Could not parse Participant")
+			}
+			key.Participant = proto.String(jid.ToNonAD().String())
+		} else if cached, found := recallMessage(userid, msgid); found {
+			key.Participant = proto.String(cached.Sender.String())
+		} else {
+			return nil, errors.New("Missing Participant in Payload, the sender of group messages is only known for messages of the last 24 hours")
+		}
+	}
+	return key, nil
+}
+
+// Builds the ContextInfo for an outgoing message from the one supplied in the request.
+// When replying, the quoted message and its sender are taken from the recent messages
+// cache so the reply renders the original content on the recipient's phone
+func buildContextInfo(userid int, ci *waE2E.ContextInfo) (*waE2E.ContextInfo, error) {
+	if ci.StanzaID == nil && ci.MentionedJID == nil {
+		return nil, nil
+	}
+
+	contextInfo := &waE2E.ContextInfo{}
+
+	if ci.StanzaID != nil {
+		contextInfo.StanzaID = proto.String(*ci.StanzaID)
+		contextInfo.Participant = ci.Participant
+
+		if quoted, found := recallMessage(userid, *ci.StanzaID); found {
+			if contextInfo.Participant == nil {
+				contextInfo.Participant = proto.String(quoted.Sender.String())
+			}
+			quotedMessage := proto.Clone(quoted.Message).(*waE2E.Message)
+			quotedMessage.MessageContextInfo = nil
+			contextInfo.QuotedMessage = quotedMessage
+		} else if ci.QuotedMessage != nil {
+			contextInfo.QuotedMessage = ci.QuotedMessage
+		} else {
+			contextInfo.QuotedMessage = &waE2E.Message{Conversation: proto.String("")}
+		}
+
+		if contextInfo.Participant == nil {
+			return nil, errors.New("Missing Participant in ContextInfo")
+		}
+	}
+
+	if ci.MentionedJID != nil {
+		contextInfo.MentionedJID = ci.MentionedJID
+	}
+
+	return contextInfo, nil
+}
+
+// Sets the ContextInfo on whichever message type is present in msg
+func setContextInfo(msg *waE2E.Message, contextInfo *waE2E.ContextInfo) {
+	if contextInfo == nil {
+		return
+	}
+	switch {
+	case msg.ExtendedTextMessage != nil:
+		msg.ExtendedTextMessage.ContextInfo = contextInfo
+	case msg.ImageMessage != nil:
+		msg.ImageMessage.ContextInfo = contextInfo
+	case msg.VideoMessage != nil:
+		msg.VideoMessage.ContextInfo = contextInfo
+	case msg.PtvMessage != nil:
+		msg.PtvMessage.ContextInfo = contextInfo
+	case msg.AudioMessage != nil:
+		msg.AudioMessage.ContextInfo = contextInfo
+	case msg.DocumentMessage != nil:
+		msg.DocumentMessage.ContextInfo = contextInfo
+	case msg.StickerMessage != nil:
+		msg.StickerMessage.ContextInfo = contextInfo
+	case msg.LocationMessage != nil:
+		msg.LocationMessage.ContextInfo = contextInfo
+	case msg.LiveLocationMessage != nil:
+		msg.LiveLocationMessage.ContextInfo = contextInfo
+	case msg.ContactMessage != nil:
+		msg.ContactMessage.ContextInfo = contextInfo
+	case msg.ContactsArrayMessage != nil:
+		msg.ContactsArrayMessage.ContextInfo = contextInfo
+	case msg.ButtonsMessage != nil:
+		msg.ButtonsMessage.ContextInfo = contextInfo
+	case msg.ListMessage != nil:
+		msg.ListMessage.ContextInfo = contextInfo
+	case msg.TemplateMessage != nil:
+		msg.TemplateMessage.ContextInfo = contextInfo
+	case msg.Conversation != nil:
+		// Plain conversation messages cannot carry context, upgrade to extended text
+		msg.ExtendedTextMessage = &waE2E.ExtendedTextMessage{Text: msg.Conversation, ContextInfo: contextInfo}
+		msg.Conversation = nil
+	}
+}
+
+// Returns the ContextInfo of whichever message type is present in msg
+func getContextInfo(msg *waE2E.Message) *waE2E.ContextInfo {
+	switch {
+	case msg.ExtendedTextMessage != nil:
+		return msg.ExtendedTextMessage.ContextInfo
+	case msg.ImageMessage != nil:
+		return msg.ImageMessage.ContextInfo
+	case msg.VideoMessage != nil:
+		return msg.VideoMessage.ContextInfo
+	case msg.PtvMessage != nil:
+		return msg.PtvMessage.ContextInfo
+	case msg.AudioMessage != nil:
+		return msg.AudioMessage.ContextInfo
+	case msg.DocumentMessage != nil:
+		return msg.DocumentMessage.ContextInfo
+	case msg.StickerMessage != nil:
+		return msg.StickerMessage.ContextInfo
+	case msg.LocationMessage != nil:
+		return msg.LocationMessage.ContextInfo
+	case msg.LiveLocationMessage != nil:
+		return msg.LiveLocationMessage.ContextInfo
+	case msg.ContactMessage != nil:
+		return msg.ContactMessage.ContextInfo
+	case msg.ContactsArrayMessage != nil:
+		return msg.ContactsArrayMessage.ContextInfo
+	case msg.ButtonsMessage != nil:
+		return msg.ButtonsMessage.ContextInfo
+	case msg.ListMessage != nil:
+		return msg.ListMessage.ContextInfo
+	case msg.TemplateMessage != nil:
+		return msg.TemplateMessage.ContextInfo
+	case msg.ButtonsResponseMessage != nil:
+		return msg.ButtonsResponseMessage.ContextInfo
+	case msg.ListResponseMessage != nil:
+		return msg.ListResponseMessage.ContextInfo
+	case msg.TemplateButtonReplyMessage != nil:
+		return msg.TemplateButtonReplyMessage.ContextInfo
+	case msg.InteractiveResponseMessage != nil:
+		return msg.InteractiveResponseMessage.ContextInfo
+	}
+	return nil
+}
+
+// Returns the JIDs of the users @mentioned in a text
+func parseMentions(text string) []string {
+	var mentions []string
+	for _, match := range mentionRegex.FindAllStringSubmatch(text, -1) {
+		mentions = append(mentions, types.NewJID(match[1], types.DefaultUserServer).String())
+	}
+	return mentions
+}
+
+// Returned by addMentions when the group participants could not be fetched, which is not
+// a problem with the request
+var errGroupParticipants = errors.New("Failed to get group participants")
+
+// Status code to answer with when addMentions fails
+func mentionErrorStatus(err error) int {
+	if errors.Is(err, errGroupParticipants) {
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// Fills MentionedJID with the users @mentioned in text and, when mentionAll is set, with
+// every member of the group. Unless hidden is set, the members mentioned through mentionAll
+// are also written as @tokens at the end of the text, which is returned
+func addMentions(client *whatsmeow.Client, contextInfo *waE2E.ContextInfo, recipient types.JID, text string, mentionAll bool, hidden bool) (*waE2E.ContextInfo, string, error) {
+	mentions := parseMentions(text)
+
+	if mentionAll {
+		if recipient.Server != types.GroupServer {
+			return nil, text, errors.New("MentionAll can only be used in group chats")
+		}
+		info, err := client.GetGroupInfo(recipient)
+		if err != nil {
+			return nil, text, fmt.Errorf("%w: %v", errGroupParticipants, err)
+		}
+		var tokens []string
+		for _, participant := range info.Participants {
+			if participant.JID.User == client.Store.ID.User || (client.Store.LID.User != "" && participant.JID.User == client.Store.LID.User) {
+				continue
+			}
+			// Groups addressed by LID list members by their LID, mention them by phone number
+			// so the visible tokens are numbers. Members whose number is unknown are still
+			// notified, but get no visible token
+			jid := participant.JID
+			if jid.Server == types.HiddenUserServer {
+				jid = participant.PhoneNumber
+				if jid.IsEmpty() {
+					jid, _ = client.Store.LIDs.GetPNForLID(context.Background(), participant.JID)
+				}
+				if jid.IsEmpty() {
+					mentions = append(mentions, participant.JID.String())
+					continue
+				}
+			}
+			mentions = append(mentions, jid.String())
+			tokens = append(tokens, "@"+jid.User)
+		}
+		if !hidden && len(tokens) > 0 {
+			if text != "" {
+				text += "\n\n"
+			}
+			text += strings.Join(tokens, " ")
+		}
+	}
+
+	if len(mentions) == 0 {
+		return contextInfo, text, nil
+	}
+	if contextInfo == nil {
+		contextInfo = &waE2E.ContextInfo{}
+	}
+
+	seen := make(map[string]bool)
+	var merged []string
+	for _, jid := range append(contextInfo.MentionedJID, mentions...) {
+		if !seen[jid] {
+			seen[jid] = true
+			merged = append(merged, jid)
+		}
+	}
+	contextInfo.MentionedJID = merged
+
+	return contextInfo, text, nil
+}
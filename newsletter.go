@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// GraphQL mutation used by WhatsApp Web to edit a newsletter. whatsmeow knows about it
+// but does not expose a method for it yet
+const newsletterUpdateQueryID = "7150902998257522"
+
+// Number of messages returned by /newsletter/messages when no count is given, and the
+// most that can be requested
+const (
+	defaultNewsletterMessages = 50
+	maxNewsletterMessages     = 100
+)
+
+// Fields of a newsletter that can be edited, nil fields are left untouched
+type newsletterUpdate struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Picture     []byte  `json:"picture,omitempty"`
+}
+
+// Updates the name, description and/or picture of a newsletter the user administers
+func updateNewsletter(client *whatsmeow.Client, jid types.JID, updates newsletterUpdate) (*types.NewsletterMetadata, error) {
+	data, err := client.DangerousInternals().SendMexIQ(context.Background(), newsletterUpdateQueryID, map[string]any{
+		"newsletter_id": jid.String(),
+		"updates":       &updates,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Newsletter *types.NewsletterMetadata `json:"xwa2_newsletter_update"`
+	}
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Newsletter, nil
+}
+
+// Uploads media for a newsletter post and builds the message. Newsletter media is not
+// encrypted, so there is no media key and the returned handle must be passed when sending
+func uploadNewsletterMedia(client *whatsmeow.Client, filedata []byte, mediaType whatsmeow.MediaType, caption string) (*waE2E.Message, string, error) {
+	uploaded, err := client.UploadNewsletter(context.Background(), filedata, mediaType)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to upload file: %v", err)
+	}
+
+	switch mediaType {
+	case whatsmeow.MediaImage:
+		img, _, err := image.Decode(bytes.NewReader(filedata))
+		if err != nil {
+			return nil, "", fmt.Errorf("Could not decode image for thumbnail preparation: %v", err)
+		}
+		thumbnailBytes, err := jpegThumbnail(img)
+		if err != nil {
+			return nil, "", err
+		}
+		return &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			Caption:       proto.String(caption),
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			Mimetype:      proto.String(http.DetectContentType(filedata)),
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			JPEGThumbnail: thumbnailBytes,
+		}}, uploaded.Handle, nil
+	case whatsmeow.MediaVideo:
+		info, err := probeVideo(filedata)
+		if err != nil {
+			log.Warn().Err(err).Msg("Could not probe video")
+		}
+		thumbnailBytes, err := videoThumbnail(filedata, info.Seconds)
+		if err != nil {
+			log.Warn().Err(err).Msg("Could not extract video thumbnail")
+		}
+		video := &waE2E.VideoMessage{
+			Caption:       proto.String(caption),
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			Mimetype:      proto.String(http.DetectContentType(filedata)),
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			JPEGThumbnail: thumbnailBytes,
+		}
+		if info.Seconds > 0 {
+			video.Seconds = proto.Uint32(info.Seconds)
+		}
+		if info.Width > 0 && info.Height > 0 {
+			video.Width = proto.Uint32(info.Width)
+			video.Height = proto.Uint32(info.Height)
+		}
+		return &waE2E.Message{VideoMessage: video}, uploaded.Handle, nil
+	}
+	return nil, "", fmt.Errorf("unsupported newsletter media type %s", mediaType)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"go.mau.fi/whatsmeow"
+)
+
+const (
+	numberCheckTTL       = 24 * time.Hour
+	numberCheckBatchSize = 500
+	maxBulkCheckNumbers  = 5000
+)
+
+// Results of IsOnWhatsApp queries keyed by normalized number. Registration does not depend
+// on who asks, so the cache is shared by all users
+var numbercache = cache.New(numberCheckTTL, time.Hour)
+
+// Length of national significant numbers (area code included, no trunk prefix) for some
+// countries, used to tell national numbers apart from numbers that already carry the
+// country code when it is missing the leading +
+var nationalNumberLengths = map[string][]int{
+	"1":   {10},     // US, Canada
+	"34":  {9},      // Spain
+	"44":  {10},     // United Kingdom
+	"52":  {10},     // Mexico
+	"54":  {10},     // Argentina
+	"55":  {10, 11}, // Brazil
+	"56":  {9},      // Chile
+	"57":  {10},     // Colombia
+	"351": {9},      // Portugal
+	"91":  {10},     // India
+}
+
+// Reports whether code looks like a country calling code: one to three digits
+func validCountryCode(code string) bool {
+	if len(code) < 1 || len(code) > 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+type numberCheck struct {
+	Query        string
+	Number       string
+	Valid        bool
+	IsInWhatsapp bool
+	JID          string
+	VerifiedName string
+	Cached       bool
+	Error        string `json:",omitempty"`
+}
+
+// Normalizes a free-form phone number (spaces, dashes, parenthesis, + or 00 prefix,
+// national format) to E.164 digits, without the leading +. Numbers in national format
+// get the default country calling code
+func normalizePhone(input string, country string) (string, error) {
+	input = strings.TrimSpace(input)
+	international := strings.HasPrefix(input, "+")
+
+	var digits strings.Builder
+	for _, c := range input {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c == ' ' || c == '-' || c == '.' || c == '(' || c == ')' || c == '/' || c == '+':
+		default:
+			return "", errors.New("invalid character in phone number")
+		}
+	}
+	number := digits.String()
+
+	if !international && strings.HasPrefix(number, "00") {
+		number = strings.TrimPrefix(number, "00")
+		international = true
+	}
+
+	if !international && country != "" {
+		national := strings.HasPrefix(number, "0")
+		number = strings.TrimLeft(number, "0")
+		if !national {
+			for _, length := range nationalNumberLengths[country] {
+				if len(number) == length {
+					national = true
+					break
+				}
+			}
+		}
+		if national || !strings.HasPrefix(number, country) {
+			number = country + number
+		}
+	}
+
+	if len(number) < 8 || len(number) > 15 {
+		return "", errors.New("invalid phone number length")
+	}
+	return number, nil
+}
+
+// Returns the numbers to ask WhatsApp about for a normalized number. Brazilian mobile
+// numbers got a ninth digit in 2016, but accounts registered before that may still be
+// registered without it, so both forms are tried
+func phoneCandidates(number string) []string {
+	if !strings.HasPrefix(number, "55") {
+		return []string{number}
+	}
+	area, subscriber := number[2:4], number[4:]
+	switch {
+	case len(subscriber) == 9 && subscriber[0] == '9':
+		return []string{number, "55" + area + subscriber[1:]}
+	case len(subscriber) == 8 && subscriber[0] >= '6':
+		return []string{number, "55" + area + "9" + subscriber}
+	}
+	return []string{number}
+}
+
+// Checks which normalized numbers are on WhatsApp, using the cache and querying the
+// rest in batches. Returns the results keyed by normalized number
+func checkNumbers(client *whatsmeow.Client, numbers []string) (map[string]numberCheck, error) {
+	results := make(map[string]numberCheck)
+	var pending []string
+	var queries []string
+	seen := make(map[string]bool)
+
+	for _, number := range numbers {
+		if seen[number] {
+			continue
+		}
+		seen[number] = true
+		if item, found := numbercache.Get(number); found {
+			check := item.(numberCheck)
+			check.Cached = true
+			results[number] = check
+			continue
+		}
+		pending = append(pending, number)
+		for _, candidate := range phoneCandidates(number) {
+			queries = append(queries, "+"+candidate)
+		}
+	}
+
+	answers := make(map[string]numberCheck)
+	for start := 0; start < len(queries); start += numberCheckBatchSize {
+		end := start + numberCheckBatchSize
+		if end > len(queries) {
+			end = len(queries)
+		}
+		resp, err := client.IsOnWhatsApp(queries[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range resp {
+			answer := numberCheck{IsInWhatsapp: item.IsIn, JID: item.JID.String()}
+			if item.VerifiedName != nil {
+				answer.VerifiedName = item.VerifiedName.Details.GetVerifiedName()
+			}
+			answers[strings.TrimPrefix(item.Query, "+")] = answer
+		}
+	}
+
+	for _, number := range pending {
+		check := numberCheck{Number: "+" + number, Valid: true}
+		for _, candidate := range phoneCandidates(number) {
+			answer, ok := answers[candidate]
+			if !ok {
+				continue
+			}
+			if answer.IsInWhatsapp {
+				check.IsInWhatsapp = true
+				check.JID = answer.JID
+				check.VerifiedName = answer.VerifiedName
+				break
+			}
+			if check.JID == "" {
+				check.JID = answer.JID
+			}
+		}
+		numbercache.Set(number, check, cache.DefaultExpiration)
+		results[number] = check
+	}
+
+	return results, nil
+}
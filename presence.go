@@ -0,0 +1,152 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Last known presence of a contact
+type PresenceState struct {
+	State     string // online, offline or unknown
+	LastSeen  *time.Time
+	UpdatedAt *time.Time
+}
+
+// Keeps, per user, the contacts we subscribed to and the last presence received from them.
+// WhatsApp forgets presence subscriptions when the connection drops, so they are saved in
+// the presence_subscriptions table and sent again every time the user connects
+type PresenceStore struct {
+	sync.RWMutex
+	subscriptions map[int]map[types.JID]bool
+	states        map[int]map[types.JID]PresenceState
+}
+
+func NewPresenceStore() *PresenceStore {
+	return &PresenceStore{
+		subscriptions: make(map[int]map[types.JID]bool),
+		states:        make(map[int]map[types.JID]PresenceState),
+	}
+}
+
+func (ps *PresenceStore) AddSubscription(userID int, jid types.JID) {
+	ps.Lock()
+	defer ps.Unlock()
+	if ps.subscriptions[userID] == nil {
+		ps.subscriptions[userID] = make(map[types.JID]bool)
+	}
+	ps.subscriptions[userID][jid.ToNonAD()] = true
+}
+
+func (ps *PresenceStore) IsSubscribed(userID int, jid types.JID) bool {
+	ps.RLock()
+	defer ps.RUnlock()
+	return ps.subscriptions[userID][jid.ToNonAD()]
+}
+
+func (ps *PresenceStore) GetSubscriptions(userID int) []types.JID {
+	ps.RLock()
+	defer ps.RUnlock()
+	var jids []types.JID
+	for jid := range ps.subscriptions[userID] {
+		jids = append(jids, jid)
+	}
+	return jids
+}
+
+func (ps *PresenceStore) Update(userID int, evt *events.Presence) {
+	ps.Lock()
+	defer ps.Unlock()
+	if ps.states[userID] == nil {
+		ps.states[userID] = make(map[types.JID]PresenceState)
+	}
+	jid := evt.From.ToNonAD()
+	now := time.Now()
+	state := ps.states[userID][jid]
+	state.UpdatedAt = &now
+	if evt.Unavailable {
+		state.State = "offline"
+		if !evt.LastSeen.IsZero() {
+			lastSeen := evt.LastSeen
+			state.LastSeen = &lastSeen
+		}
+	} else {
+		state.State = "online"
+		state.LastSeen = &now
+	}
+	ps.states[userID][jid] = state
+}
+
+func (ps *PresenceStore) Get(userID int, jid types.JID) PresenceState {
+	ps.RLock()
+	defer ps.RUnlock()
+	state, ok := ps.states[userID][jid.ToNonAD()]
+	if !ok {
+		return PresenceState{State: "unknown"}
+	}
+	return state
+}
+
+func (ps *PresenceStore) DeleteUser(userID int) {
+	ps.Lock()
+	defer ps.Unlock()
+	delete(ps.subscriptions, userID)
+	delete(ps.states, userID)
+}
+
+// Saves a presence subscription so it survives restarts
+func savePresenceSubscription(db *sqlx.DB, userID int, jid types.JID) error {
+	_, err := db.Exec("INSERT INTO presence_subscriptions (user_id, jid) VALUES ($1, $2) ON CONFLICT DO NOTHING", userID, jid.ToNonAD().String())
+	if err != nil {
+		return err
+	}
+	presenceStore.AddSubscription(userID, jid)
+	return nil
+}
+
+// Forgets the presence subscriptions of a user, when the account is logged out or removed
+func deletePresenceSubscriptions(db *sqlx.DB, userID int) {
+	if _, err := db.Exec("DELETE FROM presence_subscriptions WHERE user_id=$1", userID); err != nil {
+		log.Error().Err(err).Int("userid", userID).Msg("Could not delete presence subscriptions")
+	}
+	presenceStore.DeleteUser(userID)
+}
+
+// Loads the presence subscriptions saved for a user
+func loadPresenceSubscriptions(db *sqlx.DB, userID int) {
+	var jids []string
+	if err := db.Select(&jids, "SELECT jid FROM presence_subscriptions WHERE user_id=$1", userID); err != nil {
+		log.Warn().Err(err).Int("userid", userID).Msg("Could not load presence subscriptions")
+		return
+	}
+	for _, item := range jids {
+		jid, err := types.ParseJID(item)
+		if err != nil {
+			log.Warn().Err(err).Str("jid", item).Msg("Ignoring invalid presence subscription")
+			continue
+		}
+		presenceStore.AddSubscription(userID, jid)
+	}
+}
+
+// Subscribes again to the presence of every contact the user subscribed to, including the
+// ones saved before wuzapi was restarted
+func restorePresenceSubscriptions(client *whatsmeow.Client, db *sqlx.DB, userID int) {
+	loadPresenceSubscriptions(db, userID)
+	jids := presenceStore.GetSubscriptions(userID)
+	if len(jids) == 0 {
+		return
+	}
+	for _, jid := range jids {
+		if err := client.SubscribePresence(jid); err != nil {
+			log.Warn().Err(err).Str("jid", jid.String()).Msg("Failed to restore presence subscription")
+		}
+	}
+	log.Info().Int("count", len(jids)).Str("userid", strconv.Itoa(userID)).Msg("Presence subscriptions restored")
+}
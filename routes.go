@@ -81,6 +81,9 @@ func (s *server) routes() {
 	s.router.Handle("/webhook", c.Then(s.GetWebhook())).Methods("GET")
 	s.router.Handle("/webhook", c.Then(s.DeleteWebhook())).Methods("DELETE")
 	s.router.Handle("/webhook", c.Then(s.UpdateWebhook())).Methods("PUT")
+	s.router.Handle("/webhook/media", c.Then(s.SetMediaDelivery())).Methods("POST")
+	s.router.Handle("/webhook/media", c.Then(s.GetMediaDelivery())).Methods("GET")
+	s.router.Handle("/media/{name}", c.Then(s.GetHostedMedia())).Methods("GET")
 
 	s.router.Handle("/session/proxy", c.Then(s.SetProxy())).Methods("POST")
 
@@ -91,21 +94,56 @@ func (s *server) routes() {
 	s.router.Handle("/chat/send/document", c.Then(s.SendDocument())).Methods("POST")
 	//	s.router.Handle("/chat/send/template", c.Then(s.SendTemplate())).Methods("POST")
 	s.router.Handle("/chat/send/video", c.Then(s.SendVideo())).Methods("POST")
+	s.router.Handle("/chat/send/album", c.Then(s.SendAlbum())).Methods("POST")
 	s.router.Handle("/chat/send/sticker", c.Then(s.SendSticker())).Methods("POST")
 	s.router.Handle("/chat/send/location", c.Then(s.SendLocation())).Methods("POST")
+	s.router.Handle("/chat/send/livelocation", c.Then(s.StartLiveLocation())).Methods("POST")
+	s.router.Handle("/chat/livelocation/update", c.Then(s.UpdateLiveLocation())).Methods("POST")
+	s.router.Handle("/chat/livelocation/stop", c.Then(s.StopLiveLocation())).Methods("POST")
 	s.router.Handle("/chat/send/contact", c.Then(s.SendContact())).Methods("POST")
 	s.router.Handle("/chat/react", c.Then(s.React())).Methods("POST")
+	s.router.Handle("/chat/forward", c.Then(s.ForwardMessage())).Methods("POST")
+	s.router.Handle("/chat/pin", c.Then(s.PinMessage())).Methods("POST")
+	s.router.Handle("/chat/unpin", c.Then(s.UnpinMessage())).Methods("POST")
+	s.router.Handle("/chat/keep", c.Then(s.KeepMessage())).Methods("POST")
+	s.router.Handle("/chat/unkeep", c.Then(s.UnkeepMessage())).Methods("POST")
 	s.router.Handle("/chat/send/buttons", c.Then(s.SendButtons())).Methods("POST")
 	s.router.Handle("/chat/send/list", c.Then(s.SendList())).Methods("POST")
 
+	s.router.Handle("/status/send/text", c.Then(s.SendStatusText())).Methods("POST")
+	s.router.Handle("/status/send/image", c.Then(s.SendStatusImage())).Methods("POST")
+	s.router.Handle("/status/send/video", c.Then(s.SendStatusVideo())).Methods("POST")
+
 	s.router.Handle("/user/presence", c.Then(s.SendPresence())).Methods("POST")
+	s.router.Handle("/user/presence", c.Then(s.GetPresence())).Methods("GET")
+	s.router.Handle("/user/presence/subscribe", c.Then(s.SubscribePresence())).Methods("POST")
 	s.router.Handle("/user/info", c.Then(s.GetUser())).Methods("POST")
 	s.router.Handle("/user/check", c.Then(s.CheckUser())).Methods("POST")
+	s.router.Handle("/user/check/bulk", c.Then(s.CheckUsersBulk())).Methods("POST")
 	s.router.Handle("/user/avatar", c.Then(s.GetAvatar())).Methods("POST")
 	s.router.Handle("/user/contacts", c.Then(s.GetContacts())).Methods("GET")
+	s.router.Handle("/user/contact/{jid}", c.Then(s.GetContact())).Methods("GET")
+	s.router.Handle("/user/profile", c.Then(s.GetProfile())).Methods("GET")
+	s.router.Handle("/user/profile/name", c.Then(s.SetProfileName())).Methods("POST")
+	s.router.Handle("/user/profile/about", c.Then(s.SetProfileAbout())).Methods("POST")
+	s.router.Handle("/user/profile/picture", c.Then(s.SetProfilePicture())).Methods("POST")
+	s.router.Handle("/user/profile/picture", c.Then(s.RemoveProfilePicture())).Methods("DELETE")
+	s.router.Handle("/user/privacy", c.Then(s.GetPrivacySettings())).Methods("GET")
+	s.router.Handle("/user/privacy", c.Then(s.SetPrivacySettings())).Methods("POST")
+	s.router.Handle("/user/ephemeral", c.Then(s.SetDefaultEphemeral())).Methods("POST")
+	s.router.Handle("/user/blocklist", c.Then(s.GetBlocklist())).Methods("GET")
+	s.router.Handle("/user/block", c.Then(s.BlockUsers())).Methods("POST")
+	s.router.Handle("/user/unblock", c.Then(s.UnblockUsers())).Methods("POST")
 
 	s.router.Handle("/chat/presence", c.Then(s.ChatPresence())).Methods("POST")
 	s.router.Handle("/chat/markread", c.Then(s.MarkRead())).Methods("POST")
+	s.router.Handle("/chat/manage/archive", c.Then(s.ArchiveChat())).Methods("POST")
+	s.router.Handle("/chat/manage/pin", c.Then(s.PinChat())).Methods("POST")
+	s.router.Handle("/chat/manage/mute", c.Then(s.MuteChat())).Methods("POST")
+	s.router.Handle("/chat/manage/unread", c.Then(s.MarkChatUnread())).Methods("POST")
+	s.router.Handle("/chat/manage/clear", c.Then(s.ClearChat())).Methods("POST")
+	s.router.Handle("/chat/manage/delete", c.Then(s.DeleteChat())).Methods("POST")
+	s.router.Handle("/chat/ephemeral", c.Then(s.SetChatEphemeral())).Methods("POST")
 	s.router.Handle("/chat/downloadimage", c.Then(s.DownloadImage())).Methods("POST")
 	s.router.Handle("/chat/downloadvideo", c.Then(s.DownloadVideo())).Methods("POST")
 	s.router.Handle("/chat/downloadaudio", c.Then(s.DownloadAudio())).Methods("POST")
@@ -116,8 +154,36 @@ func (s *server) routes() {
 	s.router.Handle("/group/invitelink", c.Then(s.GetGroupInviteLink())).Methods("GET")
 	s.router.Handle("/group/photo", c.Then(s.SetGroupPhoto())).Methods("POST")
 	s.router.Handle("/group/name", c.Then(s.SetGroupName())).Methods("POST")
+	s.router.Handle("/group/create", c.Then(s.CreateGroup())).Methods("POST")
+	s.router.Handle("/group/leave", c.Then(s.LeaveGroup())).Methods("POST")
+	s.router.Handle("/group/join", c.Then(s.JoinGroup())).Methods("POST")
+	s.router.Handle("/group/inviteinfo", c.Then(s.GetGroupInviteInfo())).Methods("POST")
+	s.router.Handle("/group/participants", c.Then(s.UpdateGroupParticipants())).Methods("POST")
+	s.router.Handle("/group/announce", c.Then(s.SetGroupAnnounce())).Methods("POST")
+	s.router.Handle("/group/locked", c.Then(s.SetGroupLocked())).Methods("POST")
+	s.router.Handle("/group/topic", c.Then(s.SetGroupTopic())).Methods("POST")
+	s.router.Handle("/group/ephemeral", c.Then(s.SetGroupEphemeral())).Methods("POST")
+	s.router.Handle("/group/joinapproval", c.Then(s.SetGroupJoinApproval())).Methods("POST")
+	s.router.Handle("/group/requests", c.Then(s.GetGroupJoinRequests())).Methods("GET")
+	s.router.Handle("/group/requests/approve", c.Then(s.ApproveGroupJoinRequests())).Methods("POST")
+	s.router.Handle("/group/requests/reject", c.Then(s.RejectGroupJoinRequests())).Methods("POST")
+
+	s.router.Handle("/community/create", c.Then(s.CreateCommunity())).Methods("POST")
+	s.router.Handle("/community/link", c.Then(s.LinkCommunityGroup())).Methods("POST")
+	s.router.Handle("/community/unlink", c.Then(s.UnlinkCommunityGroup())).Methods("POST")
+	s.router.Handle("/community/subgroups", c.Then(s.ListCommunitySubGroups())).Methods("GET")
+	s.router.Handle("/community/announce", c.Then(s.SendCommunityAnnouncement())).Methods("POST")
 
 	s.router.Handle("/newsletter/list", c.Then(s.ListNewsletter())).Methods("GET")
+	s.router.Handle("/newsletter/info", c.Then(s.GetNewsletterInfo())).Methods("GET")
+	s.router.Handle("/newsletter/messages", c.Then(s.GetNewsletterMessages())).Methods("GET")
+	s.router.Handle("/newsletter/create", c.Then(s.CreateNewsletter())).Methods("POST")
+	s.router.Handle("/newsletter/update", c.Then(s.UpdateNewsletter())).Methods("POST")
+	s.router.Handle("/newsletter/follow", c.Then(s.FollowNewsletter())).Methods("POST")
+	s.router.Handle("/newsletter/unfollow", c.Then(s.UnfollowNewsletter())).Methods("POST")
+	s.router.Handle("/newsletter/mute", c.Then(s.MuteNewsletter())).Methods("POST")
+	s.router.Handle("/newsletter/subscribe", c.Then(s.SubscribeNewsletterUpdates())).Methods("POST")
+	s.router.Handle("/newsletter/send", c.Then(s.SendNewsletterMessage())).Methods("POST")
 
 	s.router.PathPrefix("/").Handler(http.FileServer(http.Dir(exPath + "/static/")))
 }
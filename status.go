@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Parses a #RRGGBB or #AARRGGBB colour into the ARGB value used by text statuses.
+// Colours without alpha are fully opaque
+func parseStatusColor(color string) (uint32, error) {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return 0, errors.New("colour must be in #RRGGBB or #AARRGGBB format")
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, errors.New("colour must be in #RRGGBB or #AARRGGBB format")
+	}
+	if len(hex) == 6 {
+		value |= 0xFF000000
+	}
+	return uint32(value), nil
+}
+
+// Parses a text status font by name (SYSTEM, FB_SCRIPT, CALISTOGA_REGULAR...) or number
+func parseStatusFont(font string) (waE2E.ExtendedTextMessage_FontType, error) {
+	if value, ok := waE2E.ExtendedTextMessage_FontType_value[strings.ToUpper(font)]; ok {
+		return waE2E.ExtendedTextMessage_FontType(value), nil
+	}
+	if value, err := strconv.Atoi(font); err == nil {
+		if _, ok := waE2E.ExtendedTextMessage_FontType_name[int32(value)]; ok {
+			return waE2E.ExtendedTextMessage_FontType(value), nil
+		}
+	}
+	return 0, errors.New("unknown font " + font)
+}
+
+// Posts a message to our own status. The audience is the one picked in the status
+// privacy settings of the account, whatsmeow takes the recipients from there
+func sendStatus(client *whatsmeow.Client, userid int, msgid string, msg *waE2E.Message) (whatsmeow.SendResponse, error) {
+	resp, err := client.SendMessage(context.Background(), types.StatusBroadcastJID, msg, whatsmeow.SendRequestExtra{ID: msgid})
+	if err != nil {
+		return resp, err
+	}
+	rememberMessage(userid, msgid, types.StatusBroadcastJID, *client.Store.ID, msg)
+	return resp, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// Phone number of a contact card. WaId is the WhatsApp number the phone links to, it
+// defaults to Number normalized as in /user/check
+type contactPhone struct {
+	Number string
+	Type   string `json:",omitempty"` // CELL, WORK, HOME, MAIN...
+	WaId   string `json:",omitempty"`
+}
+
+// Contact card, either built from the structured fields or passed as a raw Vcard
+type contactCard struct {
+	Name         string
+	FirstName    string         `json:",omitempty"`
+	LastName     string         `json:",omitempty"`
+	Organization string         `json:",omitempty"`
+	Title        string         `json:",omitempty"`
+	Phones       []contactPhone `json:",omitempty"`
+	Email        string         `json:",omitempty"`
+	Url          string         `json:",omitempty"`
+	Vcard        string         `json:",omitempty"`
+}
+
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`)
+var vcardUnescaper = strings.NewReplacer(`\\`, `\`, `\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n")
+
+// Builds a vCard 3.0 from the structured fields of a contact
+func buildVCard(card contactCard) (string, error) {
+	if card.Name == "" {
+		return "", errors.New("missing Name")
+	}
+	first, last := card.FirstName, card.LastName
+	if first == "" && last == "" {
+		first = card.Name
+	}
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\nVERSION:3.0\n")
+	fmt.Fprintf(&b, "N:%s;%s;;;\n", vcardEscaper.Replace(last), vcardEscaper.Replace(first))
+	fmt.Fprintf(&b, "FN:%s\n", vcardEscaper.Replace(card.Name))
+	if card.Organization != "" {
+		fmt.Fprintf(&b, "ORG:%s;\n", vcardEscaper.Replace(card.Organization))
+	}
+	if card.Title != "" {
+		fmt.Fprintf(&b, "TITLE:%s\n", vcardEscaper.Replace(card.Title))
+	}
+	for _, phone := range card.Phones {
+		if phone.Number == "" {
+			return "", errors.New("missing Number in Phones")
+		}
+		// Number, Type and WaId are written as is, anything that could end the line or
+		// the parameter list is rejected
+		if strings.ContainsAny(phone.Number, "\r\n") {
+			return "", errors.New("invalid Number in Phones, it cannot contain line breaks")
+		}
+		if strings.Trim(phone.Type, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz,") != "" {
+			return "", errors.New("invalid Type in Phones, only letters and commas are allowed")
+		}
+		waid := phone.WaId
+		if waid == "" {
+			// Numbers that cannot be normalized are not linked to a WhatsApp account
+			waid, _ = normalizePhone(phone.Number, *defaultCountry)
+		} else if strings.Trim(waid, "0123456789") != "" {
+			return "", errors.New("invalid WaId in Phones, only digits are allowed")
+		}
+		kind := strings.ToUpper(phone.Type)
+		if kind == "" {
+			kind = "CELL"
+		}
+		if waid == "" {
+			fmt.Fprintf(&b, "TEL;type=%s:%s\n", kind, phone.Number)
+		} else {
+			fmt.Fprintf(&b, "TEL;type=%s;waid=%s:%s\n", kind, waid, phone.Number)
+		}
+	}
+	if card.Email != "" {
+		fmt.Fprintf(&b, "EMAIL;type=INTERNET:%s\n", vcardEscaper.Replace(card.Email))
+	}
+	if card.Url != "" {
+		if strings.ContainsAny(card.Url, "\r\n") {
+			return "", errors.New("invalid Url, it cannot contain line breaks")
+		}
+		fmt.Fprintf(&b, "URL:%s\n", card.Url)
+	}
+	b.WriteString("END:VCARD")
+	return b.String(), nil
+}
+
+// Builds the ContactMessage of a card, generating the vCard unless a raw one was passed
+func buildContactMessage(card contactCard) (*waE2E.ContactMessage, error) {
+	vcard := card.Vcard
+	if vcard == "" {
+		var err error
+		if vcard, err = buildVCard(card); err != nil {
+			return nil, err
+		}
+	} else if card.Name == "" {
+		return nil, errors.New("missing Name")
+	}
+	return &waE2E.ContactMessage{
+		DisplayName: proto.String(card.Name),
+		Vcard:       proto.String(vcard),
+	}, nil
+}
+
+// Builds a message with a single contact, or a ContactsArrayMessage for several
+func buildContactsMessage(cards []contactCard) (*waE2E.Message, error) {
+	var contacts []*waE2E.ContactMessage
+	for i, card := range cards {
+		contact, err := buildContactMessage(card)
+		if err != nil {
+			return nil, fmt.Errorf("Contact %d: %v", i+1, err)
+		}
+		contacts = append(contacts, contact)
+	}
+	switch len(contacts) {
+	case 0:
+		return nil, errors.New("Missing Contacts in Payload")
+	case 1:
+		return &waE2E.Message{ContactMessage: contacts[0]}, nil
+	}
+	// Same display name WhatsApp uses when sharing several contacts
+	displayName := fmt.Sprintf("%s and 1 other contact", contacts[0].GetDisplayName())
+	if len(contacts) > 2 {
+		displayName = fmt.Sprintf("%s and %d other contacts", contacts[0].GetDisplayName(), len(contacts)-1)
+	}
+	return &waE2E.Message{ContactsArrayMessage: &waE2E.ContactsArrayMessage{
+		DisplayName: proto.String(displayName),
+		Contacts:    contacts,
+	}}, nil
+}
+
+// Parses the fields we know about from a vCard (2.1, 3.0 or 4.0). Unknown properties are
+// ignored and only the first email and URL are kept
+func parseVCard(vcard string) contactCard {
+	var card contactCard
+	// Unfold lines continued with a leading space or tab
+	vcard = strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(vcard)
+
+	for _, line := range strings.Split(vcard, "\n") {
+		line = strings.TrimRight(line, "\r")
+		sep := strings.Index(line, ":")
+		if sep < 0 {
+			continue
+		}
+		params := strings.Split(line[:sep], ";")
+		value := line[sep+1:]
+		// Apple groups related properties with an itemN. prefix
+		name := strings.ToUpper(params[0])
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			name = name[dot+1:]
+		}
+
+		switch name {
+		case "FN":
+			card.Name = vcardUnescaper.Replace(value)
+		case "N":
+			parts := splitVCardValue(value)
+			if len(parts) > 0 {
+				card.LastName = parts[0]
+			}
+			if len(parts) > 1 {
+				card.FirstName = parts[1]
+			}
+		case "ORG":
+			card.Organization = strings.TrimSpace(strings.Join(splitVCardValue(value), " "))
+		case "TITLE":
+			card.Title = vcardUnescaper.Replace(value)
+		case "TEL":
+			phone := contactPhone{Number: strings.TrimPrefix(value, "tel:")}
+			var kinds []string
+			for _, param := range params[1:] {
+				key, val, found := strings.Cut(param, "=")
+				if !found {
+					// vCard 2.1 lists types without the type= key
+					kinds = append(kinds, strings.ToUpper(key))
+					continue
+				}
+				switch strings.ToLower(key) {
+				case "waid":
+					phone.WaId = val
+				case "type":
+					kinds = append(kinds, strings.ToUpper(val))
+				}
+			}
+			phone.Type = strings.Join(kinds, ",")
+			card.Phones = append(card.Phones, phone)
+		case "EMAIL":
+			if card.Email == "" {
+				card.Email = vcardUnescaper.Replace(value)
+			}
+		case "URL":
+			if card.Url == "" {
+				card.Url = vcardUnescaper.Replace(value)
+			}
+		}
+	}
+	return card
+}
+
+// Splits a structured vCard value on unescaped semicolons
+func splitVCardValue(value string) []string {
+	var parts []string
+	var current strings.Builder
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ';':
+			parts = append(parts, vcardUnescaper.Replace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	return append(parts, vcardUnescaper.Replace(current.String()))
+}
+
+// Returns the parsed contacts of a contact or contacts array message, nil for anything else
+func parseContacts(msg *waE2E.Message) []contactCard {
+	var contacts []*waE2E.ContactMessage
+	switch {
+	case msg.GetContactMessage() != nil:
+		contacts = []*waE2E.ContactMessage{msg.GetContactMessage()}
+	case msg.GetContactsArrayMessage() != nil:
+		contacts = msg.GetContactsArrayMessage().GetContacts()
+	default:
+		return nil
+	}
+	cards := make([]contactCard, 0, len(contacts))
+	for _, contact := range contacts {
+		card := parseVCard(contact.GetVcard())
+		if card.Name == "" {
+			card.Name = contact.GetDisplayName()
+		}
+		card.Vcard = contact.GetVcard()
+		cards = append(cards, card)
+	}
+	return cards
+}
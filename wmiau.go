@@ -7,10 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"mime"
-	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -40,11 +37,21 @@ type MyClient struct {
 	token          string
 	subscriptions  []string
 	db             *sqlx.DB
+	exPath         string
+}
+
+// Event types split from Message and ReadReceipt are only used for users who subscribed
+// to them, everyone else keeps getting those events under the type they had before
+func (mycli *MyClient) eventType(newType string, oldType string) string {
+	if Find(mycli.subscriptions, newType) {
+		return newType
+	}
+	return oldType
 }
 
 // Connects to Whatsapp Websocket on server startup if last state was connected
 func (s *server) connectOnStartup() {
-	rows, err := s.db.Queryx("SELECT id,token,jid,webhook,events FROM users WHERE connected=1")
+	rows, err := s.db.Queryx("SELECT id,token,jid,webhook,events,media_delivery,media_types,media_max_size FROM users WHERE connected=1")
 	if err != nil {
 		log.Error().Err(err).Msg("DB Problem")
 		return
@@ -56,18 +63,24 @@ func (s *server) connectOnStartup() {
 		jid := ""
 		webhook := ""
 		events := ""
-		err = rows.Scan(&txtid, &token, &jid, &webhook, &events)
+		mediaDelivery := ""
+		mediaTypes := ""
+		mediaMaxSize := ""
+		err = rows.Scan(&txtid, &token, &jid, &webhook, &events, &mediaDelivery, &mediaTypes, &mediaMaxSize)
 		if err != nil {
 			log.Error().Err(err).Msg("DB Problem")
 			return
 		} else {
 			log.Info().Str("token", token).Msg("Connect to Whatsapp on startup")
 			v := Values{map[string]string{
-				"Id":      txtid,
-				"Jid":     jid,
-				"Webhook": webhook,
-				"Token":   token,
-				"Events":  events,
+				"Id":            txtid,
+				"Jid":           jid,
+				"Webhook":       webhook,
+				"Token":         token,
+				"Events":        events,
+				"MediaDelivery": mediaDelivery,
+				"MediaTypes":    mediaTypes,
+				"MediaMaxSize":  mediaMaxSize,
 			}}
 			userinfocache.Set(token, v, cache.NoExpiration)
 			userid, _ := strconv.Atoi(txtid)
@@ -121,6 +134,19 @@ func parseJID(arg string) (types.JID, bool) {
 	}
 }
 
+// Parses a list of phone numbers or JIDs
+func parseJIDList(list []string) ([]types.JID, error) {
+	var jids []types.JID
+	for _, item := range list {
+		jid, ok := parseJID(item)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Could not parse %s", item))
+		}
+		jids = append(jids, jid)
+	}
+	return jids, nil
+}
+
 func (s *server) startClient(userID int, textjid string, token string, subscriptions []string) {
 	log.Info().Str("userid", strconv.Itoa(userID)).Str("jid", textjid).Msg("Starting websocket connection to Whatsapp")
 
@@ -184,7 +210,7 @@ func (s *server) startClient(userID int, textjid string, token string, subscript
 	store.DeviceProps.Os = osName
 
 	clientManager.SetWhatsmeowClient(userID, client)
-	mycli := MyClient{client, 1, userID, token, subscriptions, s.db}
+	mycli := MyClient{client, 1, userID, token, subscriptions, s.db, s.exPath}
 	mycli.eventHandlerID = mycli.WAClient.AddEventHandler(mycli.myEventHandler)
 
 	httpClient := resty.New()
@@ -294,13 +320,44 @@ func (s *server) startClient(userID int, textjid string, token string, subscript
 	}
 }
 
-func fileToBase64(filepath string) (string, string, error) {
-	data, err := os.ReadFile(filepath)
-	if err != nil {
-		return "", "", err
+// A request to join a group that requires admin approval, or the withdrawal of one
+type groupJoinRequest struct {
+	GroupJID      types.JID
+	Action        string // created or revoked
+	Participants  []types.JID
+	RequestMethod string
+	Timestamp     time.Time
+}
+
+// Join requests are not parsed by whatsmeow, they arrive as unknown group changes
+func parseGroupJoinRequest(evt *events.GroupInfo) *groupJoinRequest {
+	for _, node := range evt.UnknownChanges {
+		var action string
+		switch node.Tag {
+		case "created_membership_requests":
+			action = "created"
+		case "revoked_membership_requests":
+			action = "revoked"
+		default:
+			continue
+		}
+		request := &groupJoinRequest{
+			GroupJID:      evt.JID,
+			Action:        action,
+			RequestMethod: node.AttrGetter().OptionalString("request_method"),
+			Timestamp:     evt.Timestamp,
+		}
+		for _, participant := range node.GetChildrenByTag("participant") {
+			if jid := participant.AttrGetter().OptionalJIDOrEmpty("jid"); !jid.IsEmpty() {
+				request.Participants = append(request.Participants, jid)
+			}
+		}
+		if len(request.Participants) == 0 && evt.Sender != nil {
+			request.Participants = append(request.Participants, *evt.Sender)
+		}
+		return request
 	}
-	mimeType := http.DetectContentType(data)
-	return base64.StdEncoding.EncodeToString(data), mimeType, nil
+	return nil
 }
 
 func (mycli *MyClient) myEventHandler(rawEvt interface{}) {
@@ -321,6 +378,11 @@ func (mycli *MyClient) myEventHandler(rawEvt interface{}) {
 			}
 		}
 	case *events.Connected, *events.PushNameSetting:
+		// Presence subscriptions are lost on reconnect, with or without a push name
+		if _, ok := rawEvt.(*events.Connected); ok {
+			go restorePresenceSubscriptions(mycli.WAClient, mycli.db, mycli.userID)
+			loadDefaultEphemeral(mycli.db, mycli.userID)
+		}
 		if len(mycli.WAClient.Store.PushName) == 0 {
 			return
 		}
@@ -363,6 +425,19 @@ func (mycli *MyClient) myEventHandler(rawEvt interface{}) {
 		return
 	case *events.Message:
 		postmap["type"] = "Message"
+		// Statuses posted by contacts are delivered as messages to status@broadcast, they
+		// are sent as Status so Message subscribers do not get them
+		if evt.Info.Chat == types.StatusBroadcastJID {
+			postmap["type"] = "Status"
+		}
+		// Live location shares and their updates carry the parsed position
+		if update := parseLiveLocation(evt); update != nil {
+			postmap["type"] = mycli.eventType("Location", "Message")
+			postmap["location"] = update
+		}
+		if contacts := parseContacts(evt.Message); contacts != nil {
+			postmap["contacts"] = contacts
+		}
 		dowebhook = 1
 		metaParts := []string{fmt.Sprintf("pushname: %s", evt.Info.PushName), fmt.Sprintf("timestamp: %s", evt.Info.Timestamp)}
 		if evt.Info.Type != "" {
@@ -379,237 +454,10 @@ func (mycli *MyClient) myEventHandler(rawEvt interface{}) {
 		}
 
 		log.Info().Str("id", evt.Info.ID).Str("source", evt.Info.SourceString()).Str("parts", strings.Join(metaParts, ", ")).Msg("Message Received")
+		rememberMessageAt(mycli.userID, evt.Info.ID, evt.Info.Chat, evt.Info.Sender, evt.Message, evt.Info.Timestamp)
+		ephemeralStore.UpdateFromMessage(mycli.userID, evt.Info.Chat, evt.Message)
 
-		if !*skipMedia {
-			// try to get Image if any
-			img := evt.Message.GetImageMessage()
-			if img != nil {
-				// Create a temporary directory in /tmp
-				tmpDirectory := filepath.Join("/tmp", "user_"+txtid)
-				errDir := os.MkdirAll(tmpDirectory, 0751)
-				if errDir != nil {
-					log.Error().Err(errDir).Msg("Could not create temporary directory")
-					return
-				}
-
-				// Download the image
-				data, err := mycli.WAClient.Download(img)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to download image")
-					return
-				}
-
-				// Determine the file extension based on the MIME type
-				exts, _ := mime.ExtensionsByType(img.GetMimetype())
-				tmpPath := filepath.Join(tmpDirectory, evt.Info.ID+exts[0])
-
-				// Write the image to the temporary file
-				err = os.WriteFile(tmpPath, data, 0600)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to save image to temporary file")
-					return
-				}
-
-				// Convert the image to base64
-				base64String, mimeType, err := fileToBase64(tmpPath)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to convert image to base64")
-					return
-				}
-
-				// Add the base64 string and other details to the postmap
-				postmap["base64"] = base64String
-				postmap["mimeType"] = mimeType
-				postmap["fileName"] = filepath.Base(tmpPath)
-
-				// Log the successful conversion
-				log.Info().Str("path", tmpPath).Msg("Image converted to base64")
-
-				// Delete the temporary file
-				err = os.Remove(tmpPath)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to delete temporary file")
-				} else {
-					log.Info().Str("path", tmpPath).Msg("Temporary file deleted")
-				}
-			}
-
-			// try to get Audio if any
-			audio := evt.Message.GetAudioMessage()
-			if audio != nil {
-				// Create a temporary directory in /tmp
-				tmpDirectory := filepath.Join("/tmp", "user_"+txtid)
-				errDir := os.MkdirAll(tmpDirectory, 0751)
-				if errDir != nil {
-					log.Error().Err(errDir).Msg("Could not create temporary directory")
-					return
-				}
-
-				// Download the audio
-				data, err := mycli.WAClient.Download(audio)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to download audio")
-					return
-				}
-
-				// Determine the file extension based on the MIME type
-				exts, _ := mime.ExtensionsByType(audio.GetMimetype())
-				var ext string
-				if len(exts) > 0 {
-					ext = exts[0]
-				} else {
-					ext = ".ogg" // Default extension if MIME type is not recognized
-				}
-				tmpPath := filepath.Join(tmpDirectory, evt.Info.ID+ext)
-
-				// Write the audio to the temporary file
-				err = os.WriteFile(tmpPath, data, 0600)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to save audio to temporary file")
-					return
-				}
-
-				// Convert the audio to base64
-				base64String, mimeType, err := fileToBase64(tmpPath)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to convert audio to base64")
-					return
-				}
-
-				// Add the base64 string and other details to the postmap
-				postmap["base64"] = base64String
-				postmap["mimeType"] = mimeType
-				postmap["fileName"] = filepath.Base(tmpPath)
-
-				// Log the successful conversion
-				log.Info().Str("path", tmpPath).Msg("Audio converted to base64")
-
-				// Delete the temporary file
-				err = os.Remove(tmpPath)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to delete temporary file")
-				} else {
-					log.Info().Str("path", tmpPath).Msg("Temporary file deleted")
-				}
-			}
-
-			// try to get Document if any
-			document := evt.Message.GetDocumentMessage()
-			if document != nil {
-				// Create a temporary directory in /tmp
-				tmpDirectory := filepath.Join("/tmp", "user_"+txtid)
-				errDir := os.MkdirAll(tmpDirectory, 0751)
-				if errDir != nil {
-					log.Error().Err(errDir).Msg("Could not create temporary directory")
-					return
-				}
-
-				// Download the document
-				data, err := mycli.WAClient.Download(document)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to download document")
-					return
-				}
-
-				// Determine the file extension
-				extension := ""
-				exts, err := mime.ExtensionsByType(document.GetMimetype())
-				if err == nil && len(exts) > 0 {
-					extension = exts[0]
-				} else {
-					filename := document.FileName
-					if filename != nil {
-						extension = filepath.Ext(*filename)
-					} else {
-						extension = ".bin" // Default extension if no filename or MIME type is available
-					}
-				}
-				tmpPath := filepath.Join(tmpDirectory, evt.Info.ID+extension)
-
-				// Write the document to the temporary file
-				err = os.WriteFile(tmpPath, data, 0600)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to save document to temporary file")
-					return
-				}
-
-				// Convert the document to base64
-				base64String, mimeType, err := fileToBase64(tmpPath)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to convert document to base64")
-					return
-				}
-
-				// Add the base64 string and other details to the postmap
-				postmap["base64"] = base64String
-				postmap["mimeType"] = mimeType
-				postmap["fileName"] = filepath.Base(tmpPath)
-
-				// Log the successful conversion
-				log.Info().Str("path", tmpPath).Msg("Document converted to base64")
-
-				// Delete the temporary file
-				err = os.Remove(tmpPath)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to delete temporary file")
-				} else {
-					log.Info().Str("path", tmpPath).Msg("Temporary file deleted")
-				}
-			}
-
-			// try to get Video if any
-			video := evt.Message.GetVideoMessage()
-			if video != nil {
-				// Create a temporary directory in /tmp
-				tmpDirectory := filepath.Join("/tmp", "user_"+txtid)
-				errDir := os.MkdirAll(tmpDirectory, 0751)
-				if errDir != nil {
-					log.Error().Err(errDir).Msg("Could not create temporary directory")
-					return
-				}
-
-				// Download the video
-				data, err := mycli.WAClient.Download(video)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to download video")
-					return
-				}
-
-				// Determine the file extension based on the MIME type
-				exts, _ := mime.ExtensionsByType(video.GetMimetype())
-				tmpPath := filepath.Join(tmpDirectory, evt.Info.ID+exts[0])
-
-				// Write the video to the temporary file
-				err = os.WriteFile(tmpPath, data, 0600)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to save video to temporary file")
-					return
-				}
-
-				// Convert the video to base64
-				base64String, mimeType, err := fileToBase64(tmpPath)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to convert video to base64")
-					return
-				}
-
-				// Add the base64 string and other details to the postmap
-				postmap["base64"] = base64String
-				postmap["mimeType"] = mimeType
-				postmap["fileName"] = filepath.Base(tmpPath)
-
-				// Log the successful conversion
-				log.Info().Str("path", tmpPath).Msg("Video converted to base64")
-
-				// Delete the temporary file
-				err = os.Remove(tmpPath)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to delete temporary file")
-				} else {
-					log.Info().Str("path", tmpPath).Msg("Temporary file deleted")
-				}
-			}
-		}
+		mycli.addIncomingMedia(postmap, evt.Info.ID, evt.Message)
 
 	case *events.Receipt:
 		postmap["type"] = "ReadReceipt"
@@ -631,9 +479,17 @@ func (mycli *MyClient) myEventHandler(rawEvt interface{}) {
 			// Discard webhooks for inactive or other delivery types
 			return
 		}
+		// Receipts for our own statuses tell who viewed them
+		if evt.Chat == types.StatusBroadcastJID {
+			postmap["type"] = "Status"
+			if postmap["state"] == "Read" {
+				postmap["state"] = "Viewed"
+			}
+		}
 	case *events.Presence:
 		postmap["type"] = "Presence"
 		dowebhook = 1
+		presenceStore.Update(mycli.userID, evt)
 		if evt.Unavailable {
 			postmap["state"] = "offline"
 			if evt.LastSeen.IsZero() {
@@ -648,10 +504,79 @@ func (mycli *MyClient) myEventHandler(rawEvt interface{}) {
 	case *events.HistorySync:
 		postmap["type"] = "HistorySync"
 		dowebhook = 1
+		ephemeralStore.UpdateFromHistory(mycli.userID, evt.Data.GetConversations())
+		rememberHistoryLastMessages(mycli.userID, *mycli.WAClient.Store.ID, evt.Data.GetConversations())
+	case *events.GroupInfo:
+		if evt.Ephemeral != nil {
+			var seconds uint32
+			if evt.Ephemeral.IsEphemeral {
+				seconds = evt.Ephemeral.DisappearingTimer
+			}
+			ephemeralStore.Set(mycli.userID, evt.JID, seconds)
+		}
+		request := parseGroupJoinRequest(evt)
+		if request == nil {
+			log.Info().Str("group", evt.JID.String()).Msg("Group info changed")
+			break
+		}
+		postmap["type"] = "GroupJoinRequest"
+		postmap["event"] = request
+		dowebhook = 1
+		log.Info().Str("group", evt.JID.String()).Str("action", request.Action).Str("participants", fmt.Sprintf("%v", request.Participants)).Msg("Group join request received")
+	case *events.PrivacySettings:
+		postmap["type"] = "PrivacySettings"
+		dowebhook = 1
+		log.Info().Str("settings", fmt.Sprintf("%+v", evt.NewSettings)).Msg("Privacy settings changed")
+	case *events.Blocklist:
+		postmap["type"] = "Blocklist"
+		dowebhook = 1
+		log.Info().Str("action", string(evt.Action)).Str("changes", fmt.Sprintf("%+v", evt.Changes)).Msg("Blocklist changed")
+	case *events.PushName:
+		postmap["type"] = "PushName"
+		dowebhook = 1
+		log.Info().Str("jid", evt.JID.String()).Str("old", evt.OldPushName).Str("new", evt.NewPushName).Msg("Push name changed")
+	case *events.BusinessName:
+		postmap["type"] = "BusinessName"
+		dowebhook = 1
+		log.Info().Str("jid", evt.JID.String()).Str("old", evt.OldBusinessName).Str("new", evt.NewBusinessName).Msg("Business name changed")
+	case *events.NewsletterJoin:
+		postmap["type"] = "NewsletterJoin"
+		dowebhook = 1
+		log.Info().Str("newsletter", evt.ID.String()).Msg("Joined newsletter")
+	case *events.NewsletterLeave:
+		postmap["type"] = "NewsletterLeave"
+		dowebhook = 1
+		log.Info().Str("newsletter", evt.ID.String()).Msg("Left newsletter")
+	case *events.NewsletterMuteChange:
+		postmap["type"] = "NewsletterMuteChange"
+		dowebhook = 1
+		log.Info().Str("newsletter", evt.ID.String()).Str("mute", string(evt.Mute)).Msg("Newsletter mute changed")
+	case *events.NewsletterLiveUpdate:
+		postmap["type"] = "NewsletterLiveUpdate"
+		dowebhook = 1
+		log.Info().Str("newsletter", evt.JID.String()).Int("messages", len(evt.Messages)).Msg("Newsletter live update received")
 	case *events.AppState:
-		log.Info().Str("index", fmt.Sprintf("%+v", evt.Index)).Str("actionValue", fmt.Sprintf("%+v", evt.SyncActionValue)).Msg("App state event received")
+		log.Debug().Strs("index", evt.Index).Msg("App state event received")
+	case *events.Archive, *events.Pin, *events.Mute, *events.MarkChatAsRead, *events.ClearChat, *events.DeleteChat:
+		setting := parseChatSetting(rawEvt)
+		// A full sync replays the state of every chat, that is not a change
+		if setting.FromFullSync {
+			log.Debug().Str("chat", setting.JID.String()).Str("action", setting.Action).Msg("Chat setting from full sync")
+			return
+		}
+		postmap["type"] = "ChatSetting"
+		postmap["event"] = setting
+		dowebhook = 1
+		log.Info().Str("chat", setting.JID.String()).Str("action", setting.Action).Msg("Chat setting changed")
 	case *events.LoggedOut:
 		log.Info().Str("reason", evt.Reason.String()).Msg("Logged out")
+		deletePresenceSubscriptions(mycli.db, mycli.userID)
+		ephemeralStore.DeleteUser(mycli.userID)
+		liveLocationStore.DeleteUser(mycli.userID)
+		// The default timer belongs to the account, the next one paired may not use it
+		if err := saveDefaultEphemeral(mycli.db, mycli.userID, 0); err != nil {
+			log.Error().Err(err).Msg("Could not reset default disappearing timer")
+		}
 		killchannel[mycli.userID] <- true
 		sqlStmt := `UPDATE users SET connected=0 WHERE id=$1`
 		_, err := mycli.db.Exec(sqlStmt, mycli.userID)